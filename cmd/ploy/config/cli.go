@@ -0,0 +1,185 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	verbose bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Manage environment variables for your application",
+		Long:  "Manage the environment variables passed to your application. Changes are rolled out without rebuilding the image",
+	}
+
+	command.AddCommand(setCommand())
+	command.AddCommand(getCommand())
+	command.AddCommand(unsetCommand())
+
+	return command
+}
+
+func setCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "set <app> NAME=value...",
+		Short: "Set environment variables",
+		Long:  "Set environment variables for your application and roll them out",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			env, err := pulumi.ParseEnv(args[1:])
+			if err != nil {
+				return err
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			config := auto.ConfigMap{}
+			for key, value := range env {
+				config[pulumi.EnvKey(key)] = auto.ConfigValue{Value: value}
+			}
+
+			err = pulumiStack.SetAllConfig(ctx, config)
+			if err != nil {
+				return fmt.Errorf("error setting config: %v", err)
+			}
+
+			log.Infof("Rolling out config for ploy application: %s", name)
+			return pulumi.Redeploy(ctx, pulumiStack, name, fmt.Sprintf("Set config %s", strings.Join(sortedKeys(env), ", ")), verbose)
+		},
+	}
+
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
+
+func getCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "get <app> [NAME]",
+		Short: "Show environment variables",
+		Long:  "Show all environment variables set with ploy config for your application, or the value of a single one",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			deploymentArgs := &pulumi.PloyDeploymentArgs{}
+			err = pulumi.LoadConfig(ctx, pulumiStack, deploymentArgs)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 2 {
+				value, ok := deploymentArgs.Config[args[1]]
+				if !ok {
+					return fmt.Errorf("%s is not set for %s", args[1], name)
+				}
+				fmt.Println(value)
+				return nil
+			}
+
+			if len(deploymentArgs.Config) == 0 {
+				log.Infof("No environment variables set for %s", name)
+				return nil
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Name", "Value"})
+			for _, key := range sortedKeys(deploymentArgs.Config) {
+				table.Append([]string{key, deploymentArgs.Config[key]})
+			}
+			table.Render()
+
+			return nil
+		},
+	}
+
+	return command
+}
+
+func unsetCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "unset <app> NAME...",
+		Short: "Remove environment variables",
+		Long:  "Remove environment variables from your application and roll out the change",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			keys := make([]string, 0, len(args[1:]))
+			for _, key := range args[1:] {
+				keys = append(keys, pulumi.EnvKey(key))
+			}
+
+			err = pulumiStack.RemoveAllConfig(ctx, keys)
+			if err != nil {
+				return fmt.Errorf("error removing config: %v", err)
+			}
+
+			log.Infof("Rolling out config for ploy application: %s", name)
+			return pulumi.Redeploy(ctx, pulumiStack, name, fmt.Sprintf("Unset config %s", strings.Join(args[1:], ", ")), verbose)
+		},
+	}
+
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
+
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
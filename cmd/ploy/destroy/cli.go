@@ -55,7 +55,7 @@ func Command() *cobra.Command {
 				os.Exit(0)
 			}
 
-			log.Debug("User confirmed, continuing: %s", result)
+			log.Debugf("User confirmed, continuing: %s", result)
 			log.Infof("Deleting application: %s", name)
 
 			// create a stack in our backend
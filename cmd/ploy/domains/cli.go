@@ -0,0 +1,218 @@
+package domains
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	verbose        bool
+	issuer         string
+	certificateArn string
+	disableTLS     bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "domains",
+		Short: "Manage custom domains for your application",
+		Long:  "Attach custom domains to your application and configure TLS certificates for them",
+	}
+
+	command.AddCommand(addCommand())
+	command.AddCommand(removeCommand())
+	command.AddCommand(listCommand())
+	command.AddCommand(tlsCommand())
+
+	return command
+}
+
+func addCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "add <app> <domain>",
+		Short: "Add a domain",
+		Long:  "Add a custom domain to your application, optionally with a TLS certificate",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			domain := strings.ToLower(args[1])
+			if err := pulumi.ValidateDomain(domain); err != nil {
+				return err
+			}
+
+			return update(cmd, args[0], fmt.Sprintf("Add domain %s", domain), func(deploymentArgs *pulumi.PloyDeploymentArgs) {
+				for _, existing := range deploymentArgs.Domains {
+					if existing == domain {
+						return
+					}
+				}
+				deploymentArgs.Domains = append(deploymentArgs.Domains, domain)
+			})
+		},
+	}
+
+	f := command.Flags()
+	f.BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+	tlsFlags(command)
+
+	return command
+}
+
+func removeCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "remove <app> <domain>",
+		Short: "Remove a domain",
+		Long:  "Remove a custom domain from your application",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			domain := strings.ToLower(args[1])
+
+			return update(cmd, args[0], fmt.Sprintf("Remove domain %s", domain), func(deploymentArgs *pulumi.PloyDeploymentArgs) {
+				var domains []string
+				for _, existing := range deploymentArgs.Domains {
+					if existing != domain {
+						domains = append(domains, existing)
+					}
+				}
+				deploymentArgs.Domains = domains
+			})
+		},
+	}
+
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
+
+func tlsCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "tls <app>",
+		Short: "Configure TLS",
+		Long:  "Configure the TLS certificate used for your application's domains",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("issuer") && !cmd.Flags().Changed("certificate-arn") && !disableTLS {
+				return fmt.Errorf("must specify --issuer, --certificate-arn or --disable")
+			}
+
+			return update(cmd, args[0], "Configure TLS", func(deploymentArgs *pulumi.PloyDeploymentArgs) {})
+		},
+	}
+
+	f := command.Flags()
+	f.BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+	f.BoolVar(&disableTLS, "disable", false, "Stop serving your application over TLS")
+	tlsFlags(command)
+
+	return command
+}
+
+func listCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "list <app>",
+		Short: "List domains",
+		Long:  "List the custom domains attached to your application",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			deploymentArgs := &pulumi.PloyDeploymentArgs{}
+			err = pulumi.LoadConfig(ctx, pulumiStack, deploymentArgs)
+			if err != nil {
+				return err
+			}
+
+			if len(deploymentArgs.Domains) == 0 {
+				log.Infof("No domains attached to %s", name)
+				return nil
+			}
+
+			var certificate string
+			if deploymentArgs.TLS != nil {
+				if deploymentArgs.TLS.Issuer != "" {
+					certificate = fmt.Sprintf("cert-manager: %s", deploymentArgs.TLS.Issuer)
+				} else {
+					certificate = fmt.Sprintf("acm: %s", deploymentArgs.TLS.CertificateArn)
+				}
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Domain", "Certificate"})
+			for _, domain := range deploymentArgs.Domains {
+				table.Append([]string{domain, certificate})
+			}
+			table.Render()
+
+			return nil
+		},
+	}
+
+	return command
+}
+
+func tlsFlags(command *cobra.Command) {
+	f := command.Flags()
+	f.StringVar(&issuer, "issuer", "", "cert-manager ClusterIssuer to request a certificate from, needs ingress mode")
+	f.StringVar(&certificateArn, "certificate-arn", "", "ACM certificate to serve from the AWS load balancer")
+}
+
+// update applies a change to the app's domains and rolls it out with the current image
+func update(cmd *cobra.Command, name string, description string, change func(*pulumi.PloyDeploymentArgs)) error {
+
+	ctx := context.Background()
+	org := viper.GetString("org")
+
+	if org == "" {
+		return fmt.Errorf("must specify pulumi org via flag or config file")
+	}
+
+	pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+	if err != nil {
+		return err
+	}
+
+	deploymentArgs, err := pulumi.LoadDeploymentArgs(ctx, pulumiStack)
+	if err != nil {
+		return err
+	}
+
+	change(deploymentArgs)
+
+	flags := cmd.Flags()
+	if flags.Lookup("disable") != nil && disableTLS {
+		deploymentArgs.TLS = nil
+	} else if flags.Changed("issuer") || flags.Changed("certificate-arn") {
+		deploymentArgs.TLS = &pulumi.TLS{Issuer: issuer, CertificateArn: certificateArn}
+	}
+
+	if err := deploymentArgs.Validate(); err != nil {
+		return err
+	}
+
+	err = pulumi.SaveDomains(ctx, pulumiStack, deploymentArgs.Domains, deploymentArgs.TLS)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Rolling out domains for ploy application: %s", name)
+	return pulumi.Update(ctx, pulumiStack, name, deploymentArgs, &pulumi.Release{Kind: pulumi.ReleaseConfig, Description: description}, verbose)
+}
@@ -0,0 +1,70 @@
+package exec
+
+import (
+	"context"
+	"os"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	tty bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "exec <app> [-- command...]",
+		Short: "Run a command in a ready pod of your application",
+		Long:  "Run a command in one of the ready pods of your application, a shell by default, attached to your terminal. Pods that are crashing or still starting are never picked",
+		Args:  cobra.MinimumNArgs(1),
+		// the command's own exit code is passed on, so there's nothing more to print
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			name := args[0]
+
+			command := args[1:]
+			if len(command) == 0 {
+				command = []string{"sh"}
+			}
+
+			kubeClient, err := kube.NewClient()
+			if err != nil {
+				return err
+			}
+
+			options := kube.ExecOptions{
+				Command: command,
+				Stdin:   os.Stdin,
+				Stdout:  os.Stdout,
+				Stderr:  os.Stderr,
+			}
+
+			// only ask for a TTY when there's a terminal on our end to drive it
+			fd := int(os.Stdin.Fd())
+			if tty && term.IsTerminal(fd) {
+				state, err := term.MakeRaw(fd)
+				if err != nil {
+					return err
+				}
+				defer term.Restore(fd, state)
+
+				sizes := newTerminalSizes(fd)
+				defer sizes.stop()
+
+				options.TTY = true
+				options.Sizes = sizes
+			}
+
+			return kubeClient.Exec(ctx, name, options)
+		},
+	}
+
+	command.Flags().BoolVarP(&tty, "tty", "t", true, "Attach a TTY when running in a terminal")
+
+	return command
+}
@@ -0,0 +1,50 @@
+package exec
+
+import (
+	"time"
+
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// terminalPollInterval is how often the local terminal is checked for a resize
+const terminalPollInterval = 250 * time.Millisecond
+
+// terminalSizes reports the size of the local terminal to the pod whenever it changes
+// It polls rather than waiting on SIGWINCH, which doesn't exist on Windows
+type terminalSizes struct {
+	fd   int
+	last remotecommand.TerminalSize
+	done chan struct{}
+}
+
+func newTerminalSizes(fd int) *terminalSizes {
+	return &terminalSizes{fd: fd, done: make(chan struct{})}
+}
+
+// Next blocks until the terminal has a new size, returning nil once the session is over
+func (t *terminalSizes) Next() *remotecommand.TerminalSize {
+	ticker := time.NewTicker(terminalPollInterval)
+	defer ticker.Stop()
+
+	for {
+		width, height, err := term.GetSize(t.fd)
+		if err == nil {
+			size := remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+			if size != t.last {
+				t.last = size
+				return &size
+			}
+		}
+
+		select {
+		case <-t.done:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *terminalSizes) stop() {
+	close(t.done)
+}
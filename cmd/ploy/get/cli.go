@@ -2,9 +2,12 @@ package get
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/jaxxstorm/ploy/pkg/kube"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
@@ -13,13 +16,66 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats, chosen with --output as -o is short for --org
+const (
+	outputTable = "table"
+	outputWide  = "wide"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputName  = "name"
+)
+
+var (
+	output  string
+	columns []string
+)
+
+// app is everything ploy get knows about a deployed application
+type app struct {
+	Name        string                 `json:"name" yaml:"name"`
+	LastUpdate  string                 `json:"lastUpdate" yaml:"lastUpdate"`
+	Result      string                 `json:"result,omitempty" yaml:"result,omitempty"`
+	ConsoleURL  string                 `json:"consoleURL" yaml:"consoleURL"`
+	URL         string                 `json:"url,omitempty" yaml:"url,omitempty"`
+	Exposure    string                 `json:"exposure" yaml:"exposure"`
+	Image       string                 `json:"image,omitempty" yaml:"image,omitempty"`
+	Autoscaling string                 `json:"autoscaling,omitempty" yaml:"autoscaling,omitempty"`
+	Outputs     map[string]interface{} `json:"outputs" yaml:"outputs"`
+}
+
+// column is a table column, chosen by its id with --columns
+type column struct {
+	id     string
+	header string
+	value  func(app) string
+}
+
+var allColumns = []column{
+	{"name", "Name", func(a app) string { return a.Name }},
+	{"last-update", "Last Update", func(a app) string { return a.LastUpdate }},
+	{"result", "Result", func(a app) string { return a.Result }},
+	{"console-url", "Deployment Info", func(a app) string { return a.ConsoleURL }},
+	{"url", "URL", func(a app) string { return a.URL }},
+	{"exposure", "Exposure", func(a app) string { return a.Exposure }},
+	{"image", "Image", func(a app) string { return a.Image }},
+	{"autoscaling", "Autoscaling", func(a app) string { return a.Autoscaling }},
+	{"endpoints", "Endpoints", func(a app) string { return strings.Join(endpointURLs(a.Outputs["endpoints"]), "\n") }},
+}
+
+var (
+	defaultColumns = []string{"name", "last-update", "console-url", "url", "exposure", "image", "autoscaling"}
+	wideColumns    = []string{"name", "last-update", "result", "console-url", "url", "exposure", "image", "autoscaling", "endpoints"}
 )
 
 func Command() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "get",
 		Short: "Get all ploy deployed applications",
-		Long:  "Get all ploy deployed applications",
+		Long: "Get all ploy deployed applications. Choose the output format with --output, which has no -o shorthand " +
+			"like kubectl's, as -o is short for --org in every ploy command",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// Required params
@@ -30,6 +86,11 @@ func Command() *cobra.Command {
 				return fmt.Errorf("must specify pulumi org via flag or config file")
 			}
 
+			selected, err := selectColumns()
+			if err != nil {
+				return err
+			}
+
 			project := workspace.Project{
 				Name:    tokens.PackageName("ploy"),
 				Runtime: workspace.NewProjectRuntimeInfo("go", nil),
@@ -47,47 +108,217 @@ func Command() *cobra.Command {
 				return fmt.Errorf("failed to list available stacks: %v", err)
 			}
 
-			if len(stackList) > 0 {
+			if len(stackList) == 0 && (output == outputTable || output == outputWide) {
+				log.Info("No ploy apps currently deployed")
+				return nil
+			}
 
-				// Build a pretty table!
-				table := tablewriter.NewWriter(os.Stdout)
-				table.SetHeader([]string{"Name", "Last Update", "Deployment Info", "URL"})
+			// Autoscaling status comes from the cluster rather than the stack, so it's best effort
+			var kubeClient *kube.Client
+			if output != outputName {
+				kubeClient, err = kube.NewClient()
+				if err != nil {
+					log.Debugf("Unable to retrieve autoscaling status: %v", err)
+				}
+			}
 
-				// Loop through all the values in the returned stacks and add them to a string array
-				for _, values := range stackList {
+			// the result of the last update costs another request per app, so it's only read when it's shown
+			withResult := output == outputJSON || output == outputYAML
+			for _, column := range selected {
+				withResult = withResult || column.id == "result"
+			}
 
-					// loop through all the stacks to retrieve the stack outputs
-					stackName := auto.FullyQualifiedStackName(org, "ploy", values.Name)
-					stack, err := auto.SelectStack(ctx, stackName, workspace)
-					if err != nil {
-						return fmt.Errorf("error selecting stack")
-					}
-					out, err := stack.Outputs(ctx)
+			apps := []app{}
+			for _, values := range stackList {
+				if output == outputName {
+					apps = append(apps, app{Name: values.Name})
+					continue
+				}
 
-					if err != nil {
-						return fmt.Errorf("no stack outputs found: %v", err)
-					}
+				a, err := describe(ctx, org, workspace, values, kubeClient, withResult)
+				if err != nil {
+					return err
+				}
+				apps = append(apps, a)
+			}
 
-					var url string
-					if out["address"].Value == nil {
-						url = ""
-					} else {
-						url = fmt.Sprintf("http://%s", out["address"].Value.(string))
-					}
+			switch output {
+			case outputName:
+				for _, a := range apps {
+					fmt.Println(a.Name)
+				}
+			case outputJSON:
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(apps)
+			case outputYAML:
+				encoder := yaml.NewEncoder(os.Stdout)
+				encoder.SetIndent(2)
+				if err := encoder.Encode(apps); err != nil {
+					return err
+				}
+				return encoder.Close()
+			default:
+				// Build a pretty table!
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetAutoWrapText(false)
+				var header []string
+				for _, column := range selected {
+					header = append(header, column.header)
+				}
+				table.SetHeader(header)
 
-					// add all the values to the output tables
-					table.Append([]string{values.Name, values.LastUpdate, values.URL, url})
+				for _, a := range apps {
+					var row []string
+					for _, column := range selected {
+						row = append(row, column.value(a))
+					}
+					table.Append(row)
 				}
 
 				// Render the table to stdout
 				table.Render()
-			} else {
-				log.Info("No ploy apps currently deployed")
 			}
 
 			return nil
 		},
 	}
 
+	var ids []string
+	for _, column := range allColumns {
+		ids = append(ids, column.id)
+	}
+
+	command.Flags().StringVar(&output, "output", outputTable, "Output format: table, wide, json, yaml or name, there's no -o shorthand as that's --org")
+	command.Flags().StringSliceVar(&columns, "columns", nil, fmt.Sprintf("Columns to show in the table, any of %s", strings.Join(ids, ", ")))
+
 	return command
 }
+
+// selectColumns returns the table columns for the output format, or the ones asked for with --columns
+func selectColumns() ([]column, error) {
+	ids := defaultColumns
+	switch output {
+	case outputTable:
+	case outputWide:
+		ids = wideColumns
+	case outputJSON, outputYAML, outputName:
+		if len(columns) > 0 {
+			return nil, fmt.Errorf("--columns only applies to table and wide output")
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %s, must be table, wide, json, yaml or name", output)
+	}
+	if len(columns) > 0 {
+		ids = columns
+	}
+
+	var selected []column
+	for _, id := range ids {
+		found := false
+		for _, column := range allColumns {
+			if strings.EqualFold(column.id, strings.TrimSpace(id)) {
+				selected = append(selected, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %s", id)
+		}
+	}
+	return selected, nil
+}
+
+// describe reads an app's outputs and last update from its stack, and its autoscaling status from the cluster
+func describe(ctx context.Context, org string, workspace auto.Workspace, values auto.StackSummary, kubeClient *kube.Client, withResult bool) (app, error) {
+	a := app{
+		Name:       values.Name,
+		LastUpdate: values.LastUpdate,
+		ConsoleURL: values.URL,
+		Outputs:    map[string]interface{}{},
+	}
+
+	// select the stack to retrieve its outputs
+	stackName := auto.FullyQualifiedStackName(org, "ploy", values.Name)
+	stack, err := auto.SelectStack(ctx, stackName, workspace)
+	if err != nil {
+		return a, fmt.Errorf("error selecting stack")
+	}
+	out, err := stack.Outputs(ctx)
+
+	if err != nil {
+		return a, fmt.Errorf("no stack outputs found: %v", err)
+	}
+
+	for key, value := range out {
+		if value.Secret {
+			a.Outputs[key] = "[secret]"
+			continue
+		}
+		a.Outputs[key] = value.Value
+	}
+
+	// apps deployed before TLS support don't export a scheme
+	scheme := "http"
+	if value, ok := out["scheme"].Value.(string); ok {
+		scheme = value
+	}
+
+	if address, ok := out["address"].Value.(string); ok && address != "" {
+		a.URL = fmt.Sprintf("%s://%s", scheme, address)
+	}
+
+	// apps deployed before exposure modes were added are public
+	a.Exposure = "public"
+	if value, ok := out["expose"].Value.(string); ok {
+		a.Exposure = value
+	}
+
+	a.Image, _ = out["ImageName"].Value.(string)
+
+	if withResult {
+		history, err := stack.History(ctx, 1, 1)
+		if err != nil {
+			log.Debugf("Unable to retrieve the last update of %s: %v", values.Name, err)
+		} else if len(history) > 0 {
+			a.Result = history[0].Result
+		}
+	}
+	if values.UpdateInProgress {
+		a.Result = "in-progress"
+	}
+
+	if kubeClient != nil {
+		a.Autoscaling, err = kubeClient.AutoscaleStatus(ctx, values.Name)
+		if err != nil {
+			log.Debugf("Unable to retrieve autoscaling status for %s: %v", values.Name, err)
+		}
+	}
+
+	return a, nil
+}
+
+// endpointURLs formats the endpoints output of an app as URLs, leaving out ports that are the default for their scheme
+func endpointURLs(value interface{}) []string {
+	endpoints, _ := value.([]interface{})
+
+	var urls []string
+	for _, value := range endpoints {
+		endpoint, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		scheme, _ := endpoint["scheme"].(string)
+		host, _ := endpoint["host"].(string)
+		port, _ := endpoint["port"].(float64)
+
+		if port == 0 || (scheme == "http" && port == 80) || (scheme == "https" && port == 443) {
+			urls = append(urls, fmt.Sprintf("%s://%s", scheme, host))
+			continue
+		}
+		urls = append(urls, fmt.Sprintf("%s://%s:%d", scheme, host, int(port)))
+	}
+	return urls
+}
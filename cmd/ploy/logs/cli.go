@@ -0,0 +1,68 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	"github.com/spf13/cobra"
+)
+
+var (
+	options    kube.LogOptions
+	jsonOutput bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "logs <app>",
+		Short: "Show the logs of your application",
+		Long:  "Show the logs of every pod of your application, prefixed with the pod they came from",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			name := args[0]
+
+			// stop following the logs on ctrl-c
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			kubeClient, err := kube.NewClient()
+			if err != nil {
+				return err
+			}
+
+			lines := make(chan kube.LogLine)
+			errs := make(chan error, 1)
+			go func() {
+				errs <- kubeClient.Logs(ctx, name, options, lines)
+			}()
+
+			// lines from every pod are written from here, so they're never interleaved mid-line
+			encoder := json.NewEncoder(os.Stdout)
+			for line := range lines {
+				if jsonOutput {
+					if err := encoder.Encode(line); err != nil {
+						return err
+					}
+					continue
+				}
+				fmt.Printf("%s %s\n", line.Pod, line.Message)
+			}
+
+			return <-errs
+		},
+	}
+
+	f := command.Flags()
+	f.BoolVarP(&options.Follow, "follow", "f", false, "Keep streaming new logs, including from pods started by a rollout")
+	f.DurationVar(&options.Since, "since", 0, "Only show logs newer than a duration, e.g. 10m")
+	f.Int64Var(&options.Tail, "tail", -1, "Number of recent lines to show from each container, all of them by default")
+	f.BoolVarP(&options.Previous, "previous", "p", false, "Show logs from the previous run of restarted containers")
+	f.BoolVar(&jsonOutput, "json", false, "Print each line as a JSON object with its pod, container and time")
+
+	return command
+}
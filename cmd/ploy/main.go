@@ -1,13 +1,27 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/jaxxstorm/ploy/cmd/ploy/config"
 	"github.com/jaxxstorm/ploy/cmd/ploy/destroy"
+	"github.com/jaxxstorm/ploy/cmd/ploy/domains"
+	"github.com/jaxxstorm/ploy/cmd/ploy/exec"
 	"github.com/jaxxstorm/ploy/cmd/ploy/get"
+	"github.com/jaxxstorm/ploy/cmd/ploy/logs"
+	"github.com/jaxxstorm/ploy/cmd/ploy/portforward"
+	"github.com/jaxxstorm/ploy/cmd/ploy/releases"
+	"github.com/jaxxstorm/ploy/cmd/ploy/restart"
+	"github.com/jaxxstorm/ploy/cmd/ploy/rollback"
+	"github.com/jaxxstorm/ploy/cmd/ploy/run"
+	"github.com/jaxxstorm/ploy/cmd/ploy/scale"
+	"github.com/jaxxstorm/ploy/cmd/ploy/secrets"
+	"github.com/jaxxstorm/ploy/cmd/ploy/status"
 	"github.com/jaxxstorm/ploy/cmd/ploy/up"
 	"github.com/jaxxstorm/ploy/pkg/contract"
+	"github.com/jaxxstorm/ploy/pkg/kube"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -28,6 +42,18 @@ func configureCLI() *cobra.Command {
 	rootCommand.AddCommand(up.Command())
 	rootCommand.AddCommand(destroy.Command())
 	rootCommand.AddCommand(get.Command())
+	rootCommand.AddCommand(config.Command())
+	rootCommand.AddCommand(secrets.Command())
+	rootCommand.AddCommand(domains.Command())
+	rootCommand.AddCommand(releases.Command())
+	rootCommand.AddCommand(rollback.Command())
+	rootCommand.AddCommand(logs.Command())
+	rootCommand.AddCommand(status.Command())
+	rootCommand.AddCommand(scale.Command())
+	rootCommand.AddCommand(restart.Command())
+	rootCommand.AddCommand(exec.Command())
+	rootCommand.AddCommand(run.Command())
+	rootCommand.AddCommand(portforward.Command())
 
 	rootCommand.PersistentFlags().StringVarP(&org, "org", "o", "", "Pulumi org to use for your stack")
 	rootCommand.PersistentFlags().StringVarP(&region, "region", "r", "us-west-2", "AWS Region to use")
@@ -64,6 +90,15 @@ func main() {
 	rootCommand := configureCLI()
 
 	if err := rootCommand.Execute(); err != nil {
+		// commands run in the cluster pass on their exit code
+		var exitErr *kube.ExitError
+		if errors.As(err, &exitErr) {
+			// the command's own output already explains its exit code, but not why it never ran
+			if exitErr.Reason != "" {
+				contract.IgnoreIoError(fmt.Fprintf(os.Stderr, "%s", err))
+			}
+			os.Exit(exitErr.Code)
+		}
 		contract.IgnoreIoError(fmt.Fprintf(os.Stderr, "%s", err))
 		os.Exit(1)
 	}
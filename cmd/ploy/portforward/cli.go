@@ -0,0 +1,101 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "port-forward <app> [local:remote]",
+		Short: "Forward a local port to your application",
+		Long: "Forward a local port to a ready pod of your application through the Kubernetes API, so you can reach it without a public address. " +
+			"The remote port defaults to the application's primary port. A Service port, by number or name, is forwarded to the pod port it targets, " +
+			"and any other port to the container port with that number or name. The local port defaults to the remote port given, or the pod port for names",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// forward until ctrl-c
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			name := args[0]
+
+			var spec string
+			if len(args) == 2 {
+				spec = args[1]
+			}
+			local, remote, err := parsePorts(spec)
+			if err != nil {
+				return err
+			}
+
+			kubeClient, err := kube.NewClient()
+			if err != nil {
+				return err
+			}
+
+			remotePort, err := kubeClient.RemotePort(ctx, name, remote)
+			if err != nil {
+				return err
+			}
+			if local == "" {
+				// like kubectl, a Service port is forwarded from the same local port rather than the one it targets
+				local = strconv.Itoa(remotePort)
+				if _, err := strconv.Atoi(remote); err == nil {
+					local = remote
+				}
+			}
+			localPort, err := strconv.Atoi(local)
+			if err != nil {
+				return fmt.Errorf("local port %s must be a number", local)
+			}
+
+			return kubeClient.PortForward(ctx, name, localPort, remotePort,
+				func(pod string, local int) {
+					log.Infof("Forwarding localhost:%d to port %d of %s, press ctrl-c to stop", local, remotePort, pod)
+				},
+				func(err error) {
+					if err != nil {
+						log.Warnf("Lost connection to %s, reconnecting: %v", name, err)
+						return
+					}
+					log.Warnf("Lost connection to %s, reconnecting", name)
+				})
+		},
+	}
+
+	return command
+}
+
+// parsePorts splits a port specification like kubectl's, local:remote, remote, or :remote for a random local port
+// The remote port can be a port name, and either side is empty if it isn't given
+func parsePorts(spec string) (string, string, error) {
+	if spec == "" {
+		return "", "", nil
+	}
+
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 1:
+		return "", parts[0], nil
+	case 2:
+		if parts[1] == "" {
+			return "", "", fmt.Errorf("invalid port %s, must be local:remote", spec)
+		}
+		local := parts[0]
+		if local == "" {
+			local = "0"
+		}
+		return local, parts[1], nil
+	}
+	return "", "", fmt.Errorf("invalid port %s, must be local:remote", spec)
+}
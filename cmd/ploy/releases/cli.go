@@ -0,0 +1,73 @@
+package releases
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "releases <app>",
+		Short: "List the releases of your application",
+		Long:  "List every release of your application, with the image and commit it rolled out",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			releases, err := pulumi.Releases(ctx, pulumiStack)
+			if err != nil {
+				return err
+			}
+
+			if len(releases) == 0 {
+				log.Infof("No releases recorded for %s", name)
+				return nil
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Release", "Date", "Kind", "Image", "Commit", "Deployed By", "Result", "Description"})
+			for _, release := range releases {
+				var commit string
+				if release.Source != nil && release.Source.Commit != "" {
+					commit = release.Source.Tag()
+				}
+
+				table.Append([]string{
+					strconv.Itoa(release.Version),
+					release.Time,
+					release.Kind,
+					release.Image,
+					commit,
+					release.DeployedBy,
+					release.Result,
+					release.Description,
+				})
+			}
+			table.Render()
+
+			return nil
+		},
+	}
+
+	return command
+}
@@ -0,0 +1,46 @@
+package restart
+
+import (
+	"context"
+	"fmt"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	verbose bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "restart <app>",
+		Short: "Restart your application",
+		Long:  "Replace every pod of your application with a rolling restart, without rebuilding its image",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			log.Infof("Restarting ploy application: %s", name)
+			return pulumi.Restart(ctx, pulumiStack, name, verbose)
+		},
+	}
+
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
@@ -0,0 +1,84 @@
+package rollback
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	verbose bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "rollback <app> [release]",
+		Short: "Roll back your application",
+		Long:  "Roll back your application to the image of an earlier release without rebuilding it, by default the last successful one that was different. Failed releases are only rolled back to when named",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			releases, err := pulumi.Releases(ctx, pulumiStack)
+			if err != nil {
+				return err
+			}
+
+			var target *pulumi.Release
+			if len(args) > 1 {
+				version, err := strconv.Atoi(args[1])
+				if err != nil {
+					return fmt.Errorf("invalid release %q: %v", args[1], err)
+				}
+				for i := range releases {
+					if releases[i].Version == version {
+						target = &releases[i]
+					}
+				}
+				if target == nil {
+					return fmt.Errorf("release %d of %s not found, see ploy releases %s", version, name, name)
+				}
+				if target.Image == "" {
+					return fmt.Errorf("release %d of %s has no image to roll back to", version, name)
+				}
+			} else {
+				outputs, err := pulumiStack.Outputs(ctx)
+				if err != nil {
+					return fmt.Errorf("no stack outputs found: %v", err)
+				}
+				current, _ := outputs["ImageName"].Value.(string)
+
+				// failed releases, including ones rolled back after an unhealthy rollout, are only used when named
+				target = pulumi.PreviousRelease(releases, current)
+				if target == nil {
+					return fmt.Errorf("no earlier release of %s to roll back to", name)
+				}
+			}
+
+			// only the image and the source it was built from change, everything else stays as it is now
+			log.Infof("Rolling back %s to release %d: %s", name, target.Version, target.Image)
+			return pulumi.Rollback(ctx, pulumiStack, name, target, 0, fmt.Sprintf("Roll back to release %d", target.Version), verbose)
+		},
+	}
+
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
@@ -0,0 +1,52 @@
+package run
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ttl time.Duration
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "run <app> -- command...",
+		Short: "Run a one-off command with your application's image",
+		Long:  "Run a one-off command as a Kubernetes Job, with the image, environment variables and secrets of your application, and exit with its exit code",
+		Args:  cobra.MinimumNArgs(2),
+		// the command's own exit code is passed on, so there's nothing more to print
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// stop waiting on ctrl-c, the job carries on until its TTL
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			name := args[0]
+
+			kubeClient, err := kube.NewClient()
+			if err != nil {
+				return err
+			}
+
+			log.Infof("Running %v for ploy application: %s", args[1:], name)
+			return kubeClient.Run(ctx, name, kube.RunOptions{
+				Command: args[1:],
+				TTL:     ttl,
+				Output:  os.Stdout,
+			})
+		},
+	}
+
+	command.Flags().DurationVar(&ttl, "ttl", time.Hour, "How long to keep the finished job and its logs before it's deleted")
+
+	return command
+}
@@ -0,0 +1,52 @@
+package scale
+
+import (
+	"context"
+	"fmt"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	replicas int
+	verbose  bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "scale <app> --replicas N",
+		Short: "Change how many pods your application runs",
+		Long:  "Change how many pods your application runs, without rebuilding its image. The count is kept for later deploys until ploy up is given --replicas",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			if !cmd.Flags().Changed("replicas") {
+				return fmt.Errorf("must specify the number of replicas with --replicas")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			log.Infof("Scaling ploy application %s to %d replicas", name, replicas)
+			return pulumi.Scale(ctx, pulumiStack, name, replicas, verbose)
+		},
+	}
+
+	command.Flags().IntVar(&replicas, "replicas", 0, "Number of pods to run")
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
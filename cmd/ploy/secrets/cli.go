@@ -0,0 +1,191 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	"github.com/manifoldco/promptui"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	verbose bool
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage secrets for your application",
+		Long:  "Manage encrypted secrets passed to your application as environment variables. Values are never displayed",
+	}
+
+	command.AddCommand(setCommand())
+	command.AddCommand(listCommand())
+	command.AddCommand(unsetCommand())
+
+	return command
+}
+
+func setCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "set <app> NAME[=value]...",
+		Short: "Set secrets",
+		Long:  "Set secrets for your application and roll them out. If a value isn't given, you'll be prompted for it",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			// prompt for any values not given on the command line, so they stay out of shell history
+			assignments := make([]string, 0, len(args[1:]))
+			for _, arg := range args[1:] {
+				if strings.Contains(arg, "=") {
+					assignments = append(assignments, arg)
+					continue
+				}
+
+				prompt := promptui.Prompt{
+					Label: fmt.Sprintf("Value for %s", arg),
+					Mask:  '*',
+				}
+				value, err := prompt.Run()
+				if err != nil {
+					return fmt.Errorf("no value given for %s: %v", arg, err)
+				}
+				assignments = append(assignments, fmt.Sprintf("%s=%s", arg, value))
+			}
+
+			secrets, err := pulumi.ParseEnv(assignments)
+			if err != nil {
+				return err
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			config := auto.ConfigMap{}
+			names := make([]string, 0, len(secrets))
+			for key, value := range secrets {
+				config[pulumi.SecretKey(key)] = auto.ConfigValue{Value: value, Secret: true}
+				names = append(names, key)
+			}
+			sort.Strings(names)
+
+			err = pulumiStack.SetAllConfig(ctx, config)
+			if err != nil {
+				return fmt.Errorf("error setting secrets: %v", err)
+			}
+
+			log.Infof("Rolling out secrets for ploy application: %s", name)
+			return pulumi.Redeploy(ctx, pulumiStack, name, fmt.Sprintf("Set secrets %s", strings.Join(names, ", ")), verbose)
+		},
+	}
+
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
+
+func listCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "list <app>",
+		Short: "List secret names",
+		Long:  "List the names of the secrets set for your application",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			deploymentArgs := &pulumi.PloyDeploymentArgs{}
+			err = pulumi.LoadConfig(ctx, pulumiStack, deploymentArgs)
+			if err != nil {
+				return err
+			}
+
+			if len(deploymentArgs.Secrets) == 0 {
+				log.Infof("No secrets set for %s", name)
+				return nil
+			}
+
+			keys := make([]string, 0, len(deploymentArgs.Secrets))
+			for key := range deploymentArgs.Secrets {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				fmt.Println(key)
+			}
+
+			return nil
+		},
+	}
+
+	return command
+}
+
+func unsetCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "unset <app> NAME...",
+		Short: "Remove secrets",
+		Long:  "Remove secrets from your application and roll out the change",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			org := viper.GetString("org")
+			name := args[0]
+
+			if org == "" {
+				return fmt.Errorf("must specify pulumi org via flag or config file")
+			}
+
+			pulumiStack, err := pulumi.SelectStack(ctx, org, name)
+			if err != nil {
+				return err
+			}
+
+			keys := make([]string, 0, len(args[1:]))
+			for _, key := range args[1:] {
+				keys = append(keys, pulumi.SecretKey(key))
+			}
+
+			err = pulumiStack.RemoveAllConfig(ctx, keys)
+			if err != nil {
+				return fmt.Errorf("error removing secrets: %v", err)
+			}
+
+			log.Infof("Rolling out secrets for ploy application: %s", name)
+			return pulumi.Redeploy(ctx, pulumiStack, name, fmt.Sprintf("Unset secrets %s", strings.Join(args[1:], ", ")), verbose)
+		},
+	}
+
+	command.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
+
+	return command
+}
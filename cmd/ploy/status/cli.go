@@ -0,0 +1,93 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func Command() *cobra.Command {
+	command := &cobra.Command{
+		Use:     "status <app>",
+		Aliases: []string{"ps"},
+		Short:   "Show the health of your application",
+		Long:    "Show the replicas and pods of your application running in the cluster, with any recent warnings",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ctx := context.Background()
+			name := args[0]
+
+			kubeClient, err := kube.NewClient()
+			if err != nil {
+				return err
+			}
+
+			status, err := kubeClient.Status(ctx, name)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Image:    %s\n", status.Image)
+			fmt.Printf("Replicas: %d desired, %d updated, %d ready, %d available\n\n", status.Desired, status.Updated, status.Ready, status.Available)
+
+			pods := tablewriter.NewWriter(os.Stdout)
+			pods.SetHeader([]string{"Pod", "Status", "Ready", "Restarts", "Node", "Age", "Outdated Image"})
+			for _, pod := range status.Pods {
+				// pods still running an older image are left over from a rollout
+				image := ""
+				if pod.Image != status.Image {
+					image = pod.Image
+				}
+
+				pods.Append([]string{
+					pod.Name,
+					pod.Status,
+					pod.Ready,
+					strconv.Itoa(int(pod.Restarts)),
+					pod.Node,
+					age(pod.Created),
+					image,
+				})
+			}
+			pods.Render()
+
+			if len(status.Events) == 0 {
+				return nil
+			}
+
+			fmt.Println("\nRecent warnings:")
+			events := tablewriter.NewWriter(os.Stdout)
+			events.SetHeader([]string{"Last Seen", "Object", "Reason", "Count", "Message"})
+			for _, event := range status.Events {
+				events.Append([]string{
+					age(event.Time),
+					event.Object,
+					event.Reason,
+					strconv.Itoa(int(event.Count)),
+					event.Message,
+				})
+			}
+			events.Render()
+
+			return nil
+		},
+	}
+
+	return command
+}
+
+// age formats how long ago something happened, like kubectl does
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return duration.HumanDuration(time.Since(t))
+}
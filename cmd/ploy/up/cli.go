@@ -3,25 +3,45 @@ package up
 import (
 	"context"
 	"fmt"
-	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/jaxxstorm/ploy/pkg/builder"
+	"github.com/jaxxstorm/ploy/pkg/manifest"
 	n "github.com/jaxxstorm/ploy/pkg/name"
 	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	"github.com/jaxxstorm/ploy/pkg/source"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
-	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
-	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	dryrun    bool
-	name      string
-	directory string
-	verbose   bool
-	nlb       bool
+	dryrun      bool
+	name        string
+	directory   string
+	verbose     bool
+	nlb         bool
+	expose      string
+	sources     []string
+	addressWait time.Duration
+	ports       []string
+	replicas    int
+	resources   pulumi.Resources
+	autoscale   string
+	ingress     bool
+	ingressArgs pulumi.Ingress
+
+	registryDockerConfig string
+	image                string
+	build                pulumi.Build
+	buildArgs            []string
+	wait                 bool
+	timeout              time.Duration
+	smokePath            string
+	rollback             bool
 )
 
 func Command() *cobra.Command {
@@ -41,17 +61,169 @@ func Command() *cobra.Command {
 				return fmt.Errorf("must specify pulumi org via flag or config file")
 			}
 
-			// If the user doesn't specify a name, generate a random one for them
-			if len(args) < 1 {
-				name = n.GenerateName()
+			// Read the app manifest if there is one, flags override anything it declares
+			appManifest, err := manifest.Load(directory)
+			if err != nil {
+				return err
+			}
+
+			var deploymentArgs *pulumi.PloyDeploymentArgs
+			if appManifest != nil {
+				log.Debugf("Using manifest %s", filepath.Join(directory, manifest.FileName))
+				deploymentArgs = appManifest.DeploymentArgs()
 			} else {
+				deploymentArgs = &pulumi.PloyDeploymentArgs{Directory: directory}
+			}
+
+			// If the user doesn't specify a name, use the manifest's or generate a random one for them
+			if len(args) > 0 {
 				name = args[0]
+			} else if appManifest != nil && appManifest.Name != "" {
+				name = appManifest.Name
+			} else {
+				name = n.GenerateName()
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("nlb") {
+				deploymentArgs.Nlb = nlb
+			}
+			if flags.Changed("expose") {
+				deploymentArgs.Expose = expose
+			}
+			if flags.Changed("source-range") {
+				deploymentArgs.SourceRanges = sources
+			}
+			deploymentArgs.AddressTimeout = addressWait
+
+			// any of the ingress flags switch the app to ingress mode, overriding the manifest's settings
+			if ingress || flags.Changed("ingress-host") || flags.Changed("ingress-path") || flags.Changed("ingress-class") {
+				if deploymentArgs.Ingress == nil {
+					deploymentArgs.Ingress = &pulumi.Ingress{}
+				}
+				if flags.Changed("ingress-host") {
+					deploymentArgs.Ingress.Host = ingressArgs.Host
+				}
+				if flags.Changed("ingress-path") {
+					deploymentArgs.Ingress.Path = ingressArgs.Path
+				}
+				if flags.Changed("ingress-class") {
+					deploymentArgs.Ingress.Class = ingressArgs.Class
+				}
+			}
+
+			if flags.Changed("port") {
+				parsedPorts, err := pulumi.ParsePorts(ports)
+				if err != nil {
+					return err
+				}
+				deploymentArgs.Ports = parsedPorts
+			}
+
+			if flags.Changed("replicas") {
+				deploymentArgs.Replicas = &replicas
+			}
+
+			if flags.Changed("autoscale") {
+				parsedAutoscale, err := pulumi.ParseAutoscale(autoscale)
+				if err != nil {
+					return err
+				}
+				deploymentArgs.Autoscale = parsedAutoscale
+			}
+
+			if flags.Changed("cpu-request") {
+				deploymentArgs.Resources.CPURequest = resources.CPURequest
+			}
+			if flags.Changed("cpu-limit") {
+				deploymentArgs.Resources.CPULimit = resources.CPULimit
+			}
+			if flags.Changed("memory-request") {
+				deploymentArgs.Resources.MemoryRequest = resources.MemoryRequest
+			}
+			if flags.Changed("memory-limit") {
+				deploymentArgs.Resources.MemoryLimit = resources.MemoryLimit
+			}
+
+			if flags.Changed("builder") {
+				deploymentArgs.Build.Builder = build.Builder
+			}
+			if flags.Changed("dockerfile") {
+				deploymentArgs.Build.Dockerfile = build.Dockerfile
+			}
+			if flags.Changed("target") {
+				deploymentArgs.Build.Target = build.Target
+			}
+			if flags.Changed("build-arg") {
+				parsedArgs, secretArgs, err := pulumi.ParseBuildArgs(buildArgs)
+				if err != nil {
+					return err
+				}
+				deploymentArgs.Build.Args = parsedArgs
+				deploymentArgs.Build.SecretArgs = secretArgs
+			}
+			if flags.Changed("cache-from") {
+				deploymentArgs.Build.CacheFrom = build.CacheFrom
+			}
+			if flags.Changed("platform") {
+				deploymentArgs.Build.Platform = build.Platform
+			}
+
+			// a prebuilt image is deployed as it is, so there's nothing to build or push
+			if flags.Changed("image") {
+				if flags.Changed("registry") {
+					return fmt.Errorf("--registry can't be used with --image, the image is pulled from where it was pushed")
+				}
+				deploymentArgs.Image = image
+				deploymentArgs.Prebuilt = true
+				deploymentArgs.Registry = nil
+			}
+
+			// the registry can also be set for every app in the ploy config file, but ploy.yaml and flags take precedence
+			if registry := viper.GetString("registry"); !deploymentArgs.Prebuilt && (flags.Changed("registry") || (deploymentArgs.Registry == nil && registry != "")) {
+				parsedRegistry, err := pulumi.ParseRegistry(registry)
+				if err != nil {
+					return err
+				}
+				deploymentArgs.Registry = parsedRegistry
 			}
 
-			// check if we have a valid Dockerfile before proceeding
-			dockerfile := fmt.Sprintf("%s/Dockerfile", directory)
-			if _, err := os.Stat(dockerfile); os.IsNotExist(err) {
-				return fmt.Errorf("no Dockerfile found in %s: %v", directory, err)
+			if deploymentArgs.Registry != nil && deploymentArgs.Registry.Type != pulumi.RegistryECR {
+				if registryDockerConfig != "" {
+					if err := deploymentArgs.Registry.LoadDockerConfig(registryDockerConfig); err != nil {
+						return err
+					}
+				}
+				if username := viper.GetString("registry-username"); username != "" {
+					deploymentArgs.Registry.Username = username
+				}
+				if password := viper.GetString("registry-password"); password != "" {
+					deploymentArgs.Registry.Password = password
+				}
+			}
+
+			if err := deploymentArgs.Validate(); err != nil {
+				return fmt.Errorf("invalid deployment settings: %v", err)
+			}
+
+			if smokePath != "" && deploymentArgs.Expose == pulumi.ExposeInternal {
+				return fmt.Errorf("smoke checks need an address reachable from here, internal applications only have one inside the cluster")
+			}
+
+			if !deploymentArgs.Prebuilt {
+				// the image is tagged with the commit it's built from, or the context's digest outside of git
+				deploymentArgs.Source, err = source.Inspect(deploymentArgs.Directory)
+				if err != nil {
+					return err
+				}
+				log.Debugf("Tagging image %s", deploymentArgs.Source.Tag())
+
+				// check we have a Dockerfile, or can build without one, before proceeding
+				cleanup, err := builder.Prepare(name, deploymentArgs, dryrun)
+				defer cleanup()
+				if err != nil {
+					return err
+				}
 			}
 
 			// Create a stack in our backend
@@ -64,6 +236,12 @@ func Command() *cobra.Command {
 				return fmt.Errorf("failed to create or select stack: %v", err)
 			}
 
+			// Restore the config from the last update, so values set with ploy config are kept
+			_, err = pulumiStack.RefreshConfig(ctx)
+			if err != nil {
+				log.Debugf("No previous config found for %s: %v", name, err)
+			}
+
 			// set the AWS region from config
 			err = pulumiStack.SetConfig(ctx, "aws:region", auto.ConfigValue{Value: region})
 			if err != nil {
@@ -76,41 +254,69 @@ func Command() *cobra.Command {
 				return err
 			}
 
-			// Set up the workspace and install all the required plugins the user needs
-			workspace := pulumiStack.Workspace()
-
-			err = pulumi.EnsurePlugins(workspace)
-
+			// Pick up the environment variables set with ploy config
+			err = pulumi.LoadConfig(ctx, pulumiStack, deploymentArgs)
 			if err != nil {
 				return err
 			}
 
-			// Now, we set the pulumi program that is going to run
-			workspace.SetProgram(pulumi.Deploy(name, directory, nlb))
+			// an explicit --replicas replaces the count set with ploy scale
+			if flags.Changed("replicas") {
+				deploymentArgs.Replicas = &replicas
+				err = pulumi.SaveReplicas(ctx, pulumiStack, nil)
+				if err != nil {
+					return fmt.Errorf("error saving replicas: %v", err)
+				}
+			}
+
+			// settings managed by other commands, like domains, are only known now
+			if err := deploymentArgs.Validate(); err != nil {
+				return fmt.Errorf("invalid deployment settings: %v", err)
+			}
+
+			if registry := deploymentArgs.Registry; registry != nil && registry.Username != "" && registry.Password == "" {
+				return fmt.Errorf("registry username %s needs a password, set PLOY_REGISTRY_PASSWORD", registry.Username)
+			}
 
 			if dryrun {
+				// Set up the workspace and install all the required plugins the user needs
+				workspace := pulumiStack.Workspace()
+
+				err = pulumi.EnsurePlugins(workspace)
+
+				if err != nil {
+					return err
+				}
+
+				// prebuilt images keep the repository of an app ploy built before, like updates do
+				if deploymentArgs.Prebuilt {
+					deploymentArgs.KeepRepository, err = pulumi.ManagesRepository(ctx, pulumiStack)
+					if err != nil {
+						return err
+					}
+				}
+
+				// Now, we set the pulumi program that is going to run
+				workspace.SetProgram(pulumi.Deploy(name, deploymentArgs))
+
 				_, err = pulumiStack.Preview(ctx, optpreview.Message("Running ploy dryrun"))
 				if err != nil {
 					return fmt.Errorf("error creating stack: %v", err)
 				}
 			} else {
-				// Wire up our update to stream progress to stdout
-				// We give the user the option to actually view the Pulumi output
-				var streamer optup.Option
-				if verbose {
-					streamer = optup.ProgressStreams(os.Stdout)
-				} else {
-					upChannel := make(chan events.EngineEvent)
-					go collectEvents(upChannel)
+				// the rollout is watched by the gate below, rather than by Pulumi
+				wait = wait || smokePath != ""
+				deploymentArgs.SkipAwait = wait
 
-					streamer = optup.EventStreams(upChannel)
-				}
 				log.Infof("Creating ploy application: %s", name)
-				_, err = pulumiStack.Up(ctx, streamer)
-
+				err = pulumi.Update(ctx, pulumiStack, name, deploymentArgs, &pulumi.Release{Kind: pulumi.ReleaseDeploy}, verbose)
 				if err != nil {
 					return err
 				}
+
+				if wait {
+					return verifyRollout(ctx, pulumiStack, name, timeout, smokePath, rollback, verbose)
+				}
 			}
 
 			return nil
@@ -123,55 +329,38 @@ func Command() *cobra.Command {
 	f.BoolVarP(&verbose, "verbose", "v", false, "Show output of Pulumi operations")
 	f.StringVarP(&directory, "dir", "d", ".", "Path to docker context to use")
 	f.BoolVar(&nlb, "nlb", false, "Provision an NLB instead of ELB")
+	f.StringVar(&expose, "expose", pulumi.ExposePublic, "How to expose your application: internal to the cluster, private to the VPC, or public")
+	f.StringSliceVar(&sources, "source-range", nil, "CIDR allowed to reach a public application, can be repeated, e.g. 203.0.113.0/24")
+	f.DurationVar(&addressWait, "address-timeout", pulumi.DefaultAddressTimeout, "How long to wait for the load balancer to get an address")
+	f.BoolVar(&ingress, "ingress", false, "Route traffic through an Ingress instead of provisioning a load balancer")
+	f.StringVar(&ingressArgs.Host, "ingress-host", "", "Host to route to your application through the Ingress")
+	f.StringVar(&ingressArgs.Path, "ingress-path", "/", "Path prefix to route to your application through the Ingress")
+	f.StringVar(&ingressArgs.Class, "ingress-class", "", "Ingress class to use, e.g. nginx or alb")
+	f.IntVar(&replicas, "replicas", pulumi.DefaultReplicas, "Number of pods to run")
+	f.StringVar(&autoscale, "autoscale", "", "Autoscale pods instead of running a fixed number, e.g. min=2,max=10,cpu=70")
+	f.StringVar(&resources.CPURequest, "cpu-request", "", "CPU to request for each pod, e.g. 250m")
+	f.StringVar(&resources.CPULimit, "cpu-limit", "", "CPU limit for each pod, e.g. 1")
+	f.StringVar(&resources.MemoryRequest, "memory-request", "", "Memory to request for each pod, e.g. 128Mi")
+	f.StringVar(&resources.MemoryLimit, "memory-limit", "", "Memory limit for each pod, e.g. 512Mi")
+	f.StringSliceVar(&ports, "port", nil, "Port to expose as [name=][servicePort:]port[/protocol], may be repeated (default http=80/http)")
+	f.StringVar(&build.Builder, "builder", "", "Builder to use: dockerfile, buildpacks, go, node or python (default detected from the docker context)")
+	f.StringVar(&build.Dockerfile, "dockerfile", "", "Path to the Dockerfile to build (default Dockerfile in the docker context)")
+	f.StringVar(&build.Target, "target", "", "Stage of a multi-stage Dockerfile to build")
+	f.StringArrayVar(&buildArgs, "build-arg", nil, "Build arg as KEY=value, or KEY to pass a secret from the environment without storing it, may be repeated")
+	f.StringSliceVar(&build.CacheFrom, "cache-from", nil, "Image to use as a build cache, may be repeated")
+	f.StringVar(&build.Platform, "platform", "", "Platform to build the image for, e.g. linux/arm64")
+	f.BoolVar(&wait, "wait", false, "Wait for every pod to roll out and be ready, rolling back if they don't")
+	f.DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for the rollout and smoke check")
+	f.StringVar(&smokePath, "smoke-check", "", "Path to request from your application once it's rolled out, e.g. /healthz, implies --wait")
+	f.BoolVar(&rollback, "rollback", true, "Roll back to the previous image if the rollout or smoke check fails")
+	f.StringVar(&image, "image", "", "Deploy an image that's already been pushed, e.g. registry/app@sha256:..., instead of building one")
+	f.String("registry", "", "Registry to push images to: ecr, local or an address such as ghcr.io/acme or localhost:5000 (default ecr)")
+	f.String("registry-username", "", "Username to push to the registry with, the password is read from PLOY_REGISTRY_PASSWORD")
+	f.StringVar(&registryDockerConfig, "registry-docker-config", "", "Docker config file to read the registry credentials from, e.g. ~/.docker/config.json")
 
-	return command
-}
-
-func collectEvents(eventChannel <-chan events.EngineEvent) {
-
-	for {
-
-		var event events.EngineEvent
-		var ok bool
-
-		createLogger := log.WithFields(log.Fields{"event": "CREATING"})
-		completeLogger := log.WithFields(log.Fields{"event": "COMPLETE"})
-
-		event, ok = <-eventChannel
-		if !ok {
-			return
-		}
-
-		if event.ResourcePreEvent != nil {
+	viper.BindPFlag("registry", f.Lookup("registry"))
+	viper.BindPFlag("registry-username", f.Lookup("registry-username"))
+	viper.BindEnv("registry-password", "PLOY_REGISTRY_PASSWORD")
 
-			switch event.ResourcePreEvent.Metadata.Type {
-			case "aws:ecr/repository:Repository":
-				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating ECR repository")
-			case "kubernetes:core/v1:Namespace":
-				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Namespace")
-			case "kubernetes:core/v1:Service":
-				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Service")
-			case "kubernetes:apps/v1:Deployment":
-				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Deployment")
-			case "docker:image:Image":
-				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Docker Image")
-			}
-		}
-
-		if event.ResOutputsEvent != nil {
-			switch event.ResOutputsEvent.Metadata.Type {
-			case "aws:ecr/repository:Repository":
-				completeLogger.WithFields(log.Fields{"name": event.ResOutputsEvent.Metadata.New.Outputs["repositoryUrl"], "resource": event.ResOutputsEvent.Metadata.Type}).Info("Created ECR repository")
-			case "kubernetes:core/v1:Namespace":
-				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Namespace")
-			case "kubernetes:core/v1:Service":
-				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Service")
-			case "kubernetes:apps/v1:Deployment":
-				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Deployment")
-			case "docker:image:Image":
-				completeLogger.WithFields(log.Fields{"name": event.ResOutputsEvent.Metadata.New.Outputs["baseImageName"], "resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Docker Image")
-			}
-
-		}
-	}
+	return command
 }
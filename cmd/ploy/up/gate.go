@@ -0,0 +1,130 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	log "github.com/sirupsen/logrus"
+)
+
+// smokeCheckInterval is how long to wait between smoke check attempts, while DNS and load balancers catch up
+const smokeCheckInterval = 5 * time.Second
+
+// verifyRollout waits for the app's pods to roll out and optionally smoke checks it over HTTP
+// If either fails within the timeout, the app is rolled back to its previous image and the error
+// includes the events and logs of its pods
+func verifyRollout(ctx context.Context, stack auto.Stack, name string, timeout time.Duration, smokePath string, rollback bool, verbose bool) error {
+	kubeClient, err := kube.NewClient()
+	if err != nil {
+		return err
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Infof("Waiting for %s to roll out", name)
+	err = kubeClient.WaitForRollout(checkCtx, name)
+	if err == nil && smokePath != "" {
+		err = smokeCheck(checkCtx, stack, smokePath)
+	}
+	if err == nil {
+		log.Infof("Rollout of %s is healthy", name)
+		return nil
+	}
+
+	// the failing pods are described before rolling back replaces them
+	diagnostics := kubeClient.Diagnose(ctx, name)
+
+	if !rollback {
+		return fmt.Errorf("rollout of %s failed: %v\n\n%s", name, err, diagnostics)
+	}
+
+	releases, releasesErr := pulumi.Releases(ctx, stack)
+	if releasesErr != nil || len(releases) == 0 {
+		return fmt.Errorf("rollout of %s failed and it couldn't be rolled back: %v\n\n%s", name, err, diagnostics)
+	}
+	failed := releases[0]
+	target := pulumi.PreviousRelease(releases, failed.Image)
+	if target == nil {
+		return fmt.Errorf("rollout of %s failed and there's no earlier release to roll back to: %v\n\n%s", name, err, diagnostics)
+	}
+
+	log.Warnf("Rollout of %s failed, rolling back to release %d: %s", name, target.Version, target.Image)
+	rollbackErr := pulumi.Rollback(ctx, stack, name, target, failed.Version, fmt.Sprintf("Automatic rollback of release %d", failed.Version), verbose)
+	if rollbackErr != nil {
+		return fmt.Errorf("rollout of %s failed: %v, and rolling back failed: %v\n\n%s", name, err, rollbackErr, diagnostics)
+	}
+
+	return fmt.Errorf("rollout of %s failed and it was rolled back to release %d: %v\n\n%s", name, target.Version, err, diagnostics)
+}
+
+// smokeCheck requests a path from the app's primary endpoint until it responds without an error status
+// In ingress mode the path is relative to the app's ingress path, which is where its routes start
+func smokeCheck(ctx context.Context, stack auto.Stack, path string) error {
+	outputs, err := stack.Outputs(ctx)
+	if err != nil {
+		return fmt.Errorf("no stack outputs found: %v", err)
+	}
+	endpoints, _ := outputs["endpoints"].Value.([]interface{})
+	if len(endpoints) == 0 {
+		return fmt.Errorf("no address to smoke check")
+	}
+	endpoint, _ := endpoints[0].(map[string]interface{})
+	host, _ := endpoint["host"].(string)
+	if host == "" {
+		return fmt.Errorf("no address to smoke check")
+	}
+
+	// application protocols like grpc or ws are still requested over plain HTTP
+	scheme, _ := endpoint["scheme"].(string)
+	if scheme != "https" {
+		scheme = "http"
+	}
+	address := host
+	if port, _ := endpoint["port"].(float64); port != 0 && !((scheme == "http" && port == 80) || (scheme == "https" && port == 443)) {
+		address = fmt.Sprintf("%s:%d", host, int(port))
+	}
+
+	settings, err := pulumi.LoadDeploymentArgs(ctx, stack)
+	if err != nil {
+		return err
+	}
+	prefix := ""
+	if settings.Ingress != nil {
+		prefix = strings.TrimSuffix(settings.Ingress.Path, "/")
+	}
+	url := fmt.Sprintf("%s://%s%s/%s", scheme, address, prefix, strings.TrimPrefix(path, "/"))
+
+	log.Infof("Smoke checking %s", url)
+	client := &http.Client{Timeout: 10 * time.Second}
+	var lastErr error
+	for {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		response, err := client.Do(request)
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode < 400 {
+				return nil
+			}
+			err = fmt.Errorf("%s responded with %s", url, response.Status)
+		}
+		lastErr = err
+		log.Debugf("Smoke check failed: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("smoke check failed: %v", lastErr)
+		case <-time.After(smokeCheckInterval):
+		}
+	}
+}
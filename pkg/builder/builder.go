@@ -0,0 +1,244 @@
+package builder
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultBuildpacksBuilder is the Cloud Native Buildpacks builder used when none is pinned
+const DefaultBuildpacksBuilder = "paketobuildpacks/builder-jammy-base"
+
+var (
+	goVersion   = regexp.MustCompile(`(?m)^go (\d+\.\d+)`)
+	packageMain = regexp.MustCompile(`(?m)^package main\b`)
+)
+
+// Detect picks a builder for a build context, preferring its Dockerfile
+// Without one, the project type is detected from the files at the root of the context
+func Detect(directory string, dockerfile string) (string, error) {
+	if exists(dockerfile) {
+		return pulumi.BuilderDockerfile, nil
+	}
+
+	switch {
+	case exists(filepath.Join(directory, "go.mod")):
+		return pulumi.BuilderGo, nil
+	case exists(filepath.Join(directory, "package.json")):
+		return pulumi.BuilderNode, nil
+	case exists(filepath.Join(directory, "requirements.txt")):
+		return pulumi.BuilderPython, nil
+	}
+
+	return "", fmt.Errorf("no Dockerfile found at %s, and %s isn't a Go, Node or Python project, add a Dockerfile or pin a builder in ploy.yaml", dockerfile, directory)
+}
+
+// Prepare gets the build ready for the builder in use, reporting which one it is
+// Generated Dockerfiles are written to a temporary directory, which the returned function removes
+// Previews don't run pack, as they only show what would change and the build can take minutes
+func Prepare(name string, args *pulumi.PloyDeploymentArgs, preview bool) (func(), error) {
+	cleanup := func() {}
+
+	builder := args.Build.Builder
+	if builder == "" {
+		detected, err := Detect(args.Directory, args.Dockerfile())
+		if err != nil {
+			return cleanup, err
+		}
+		builder = detected
+		log.Infof("Detected %s builder for %s", builder, args.Directory)
+	} else {
+		log.Infof("Using %s builder pinned for %s", builder, name)
+	}
+
+	var dockerfile string
+	switch builder {
+	case pulumi.BuilderDockerfile:
+		if !exists(args.Dockerfile()) {
+			return cleanup, fmt.Errorf("no Dockerfile found at %s", args.Dockerfile())
+		}
+		return cleanup, nil
+	case pulumi.BuilderBuildpacks:
+		if preview {
+			// docker still builds during a preview, so it gets a placeholder in place of the pack image
+			log.Infof("Skipping the buildpacks build of %s for the preview", name)
+			dockerfile = "FROM scratch\nLABEL app.getploy.io/preview=\"true\"\n"
+			break
+		}
+		image, err := pack(name, args)
+		if err != nil {
+			return cleanup, err
+		}
+		// docker only needs to tag and push the image pack built
+		dockerfile = fmt.Sprintf("FROM %s\n", image)
+	default:
+		generated, err := generate(builder, args)
+		if err != nil {
+			return cleanup, err
+		}
+		dockerfile = generated
+	}
+
+	dir, err := ioutil.TempDir("", "ploy-build")
+	if err != nil {
+		return cleanup, fmt.Errorf("error creating build directory: %v", err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, "Dockerfile")
+	if err := ioutil.WriteFile(path, []byte(dockerfile), 0644); err != nil {
+		return cleanup, fmt.Errorf("error writing generated Dockerfile: %v", err)
+	}
+	log.Debugf("Generated Dockerfile for %s:\n%s", name, dockerfile)
+	args.Build.GeneratedDockerfile = path
+
+	return cleanup, nil
+}
+
+// pack builds the app with Cloud Native Buildpacks into a local image
+func pack(name string, args *pulumi.PloyDeploymentArgs) (string, error) {
+	if _, err := exec.LookPath("pack"); err != nil {
+		return "", fmt.Errorf("the buildpacks builder needs the pack CLI, see https://buildpacks.io/docs/tools/pack/")
+	}
+
+	builderImage := args.Build.BuildpacksBuilder
+	if builderImage == "" {
+		builderImage = DefaultBuildpacksBuilder
+	}
+
+	tag := "latest"
+	if args.Source != nil {
+		tag = args.Source.Tag()
+	}
+	image := fmt.Sprintf("ploy-%s:%s", name, tag)
+
+	packArgs := []string{"build", image, "--builder", builderImage, "--path", args.Directory}
+	for key, value := range args.Build.Args {
+		packArgs = append(packArgs, "--env", fmt.Sprintf("%s=%s", key, value))
+	}
+	// like docker, pack reads values for variables given without one from its environment
+	for _, key := range args.Build.SecretArgs {
+		packArgs = append(packArgs, "--env", key)
+	}
+
+	log.Infof("Building %s with buildpacks from %s", image, builderImage)
+	cmd := exec.Command("pack", packArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("error building with buildpacks: %v", err)
+	}
+
+	return image, nil
+}
+
+// generate renders the Dockerfile template for a project type
+func generate(builder string, args *pulumi.PloyDeploymentArgs) (string, error) {
+	data := templateData{
+		Port:    args.PrimaryPort().Port,
+		Command: procfileCommand(args.Directory),
+	}
+
+	switch builder {
+	case pulumi.BuilderGo:
+		data.Version = "1"
+		if mod, err := ioutil.ReadFile(filepath.Join(args.Directory, "go.mod")); err == nil {
+			if match := goVersion.FindSubmatch(mod); match != nil {
+				data.Version = string(match[1])
+			}
+		}
+		pkg, err := mainPackage(args.Directory)
+		if err != nil {
+			return "", err
+		}
+		data.Package = pkg
+	case pulumi.BuilderNode:
+		data.Lockfile = exists(filepath.Join(args.Directory, "package-lock.json"))
+		if data.Command == "" {
+			data.Command = "npm start"
+		}
+	case pulumi.BuilderPython:
+		if data.Command == "" {
+			for _, file := range []string{"app.py", "main.py"} {
+				if exists(filepath.Join(args.Directory, file)) {
+					data.Command = fmt.Sprintf("python %s", file)
+					break
+				}
+			}
+		}
+		if data.Command == "" {
+			return "", fmt.Errorf("no way to start the Python app, add a Procfile with a web process, an app.py or a main.py")
+		}
+	}
+
+	return render(builder, data)
+}
+
+// mainPackage finds the Go package to build, either the root of the module or its only command
+func mainPackage(directory string) (string, error) {
+	if hasMain(directory) {
+		return ".", nil
+	}
+
+	commands, _ := filepath.Glob(filepath.Join(directory, "cmd", "*"))
+	var found []string
+	for _, command := range commands {
+		if hasMain(command) {
+			found = append(found, command)
+		}
+	}
+	if len(found) == 1 {
+		rel, err := filepath.Rel(directory, found[0])
+		if err != nil {
+			return "", err
+		}
+		return "./" + filepath.ToSlash(rel), nil
+	}
+
+	return "", fmt.Errorf("unable to find the Go main package to build, add a Dockerfile to build it")
+}
+
+// hasMain reports whether a directory holds a Go main package
+func hasMain(directory string) bool {
+	files, _ := filepath.Glob(filepath.Join(directory, "*.go"))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err == nil && packageMain.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// procfileCommand returns the web process from a Heroku style Procfile, if there is one
+func procfileCommand(directory string) string {
+	file, err := os.Open(filepath.Join(directory, "Procfile"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if command := strings.TrimPrefix(scanner.Text(), "web:"); command != scanner.Text() {
+			return strings.TrimSpace(command)
+		}
+	}
+	return ""
+}
+
+func exists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
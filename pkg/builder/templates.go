@@ -0,0 +1,68 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// templateData fills in the generated Dockerfiles
+type templateData struct {
+	Port     int
+	Command  string
+	Version  string
+	Package  string
+	Lockfile bool
+}
+
+// Apps are expected to listen on $PORT, as they would on Heroku
+// Go apps run on a distroless image without a shell, so a Procfile can't be used for them
+var templates = map[string]*template.Template{
+	"go": template.Must(template.New("go").Parse(`FROM golang:{{ .Version }} AS build
+WORKDIR /src
+COPY go.mod go.sum* ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -o /app {{ .Package }}
+
+FROM gcr.io/distroless/static-debian12
+COPY --from=build /app /app
+ENV PORT={{ .Port }}
+EXPOSE {{ .Port }}
+ENTRYPOINT ["/app"]
+`)),
+	"node": template.Must(template.New("node").Parse(`FROM node:20-slim
+WORKDIR /app
+ENV NODE_ENV=production
+COPY package*.json ./
+RUN {{ if .Lockfile }}npm ci --omit=dev{{ else }}npm install --omit=dev{{ end }}
+COPY . .
+ENV PORT={{ .Port }}
+EXPOSE {{ .Port }}
+CMD ["/bin/sh", "-c", {{ printf "%q" .Command }}]
+`)),
+	"python": template.Must(template.New("python").Parse(`FROM python:3.12-slim
+WORKDIR /app
+ENV PYTHONUNBUFFERED=1
+COPY requirements.txt ./
+RUN pip install --no-cache-dir -r requirements.txt
+COPY . .
+ENV PORT={{ .Port }}
+EXPOSE {{ .Port }}
+CMD ["/bin/sh", "-c", {{ printf "%q" .Command }}]
+`)),
+}
+
+// render generates the Dockerfile for a project type
+func render(builder string, data templateData) (string, error) {
+	tmpl, ok := templates[builder]
+	if !ok {
+		return "", fmt.Errorf("no Dockerfile template for the %s builder", builder)
+	}
+
+	var dockerfile bytes.Buffer
+	if err := tmpl.Execute(&dockerfile, data); err != nil {
+		return "", fmt.Errorf("error generating Dockerfile: %v", err)
+	}
+	return dockerfile.String(), nil
+}
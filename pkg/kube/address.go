@@ -0,0 +1,73 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// addressPollInterval is how often a load balancer is checked for an address while waiting on it
+const addressPollInterval = 5 * time.Second
+
+// WaitForServiceAddress waits until the load balancer of a Service has a hostname or IP, returning it
+// Only LoadBalancer Services get an address this way, and load balancers can take minutes to provision
+func (c *Client) WaitForServiceAddress(ctx context.Context, namespace string, name string) (string, error) {
+	return waitForAddress(ctx, name, func() ([]corev1.LoadBalancerIngress, error) {
+		service, err := c.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return service.Status.LoadBalancer.Ingress, nil
+	})
+}
+
+// WaitForIngressAddress waits until the load balancer of an Ingress has a hostname or IP, returning it
+func (c *Client) WaitForIngressAddress(ctx context.Context, namespace string, name string) (string, error) {
+	return waitForAddress(ctx, name, func() ([]corev1.LoadBalancerIngress, error) {
+		ingress, err := c.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return ingressStatus(ingress.Status.LoadBalancer.Ingress), nil
+	})
+}
+
+// waitForAddress polls a load balancer status until it has an address or the context is done
+func waitForAddress(ctx context.Context, name string, status func() ([]corev1.LoadBalancerIngress, error)) (string, error) {
+	ticker := time.NewTicker(addressPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ingress, err := status()
+		if err != nil && ctx.Err() == nil {
+			return "", err
+		}
+		for _, lb := range ingress {
+			if lb.Hostname != "" {
+				return lb.Hostname, nil
+			}
+			if lb.IP != "" {
+				return lb.IP, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for the load balancer of %s to get an address", name)
+		case <-ticker.C:
+		}
+	}
+}
+
+// ingressStatus converts the load balancer status of an Ingress to the same form as a Service's
+func ingressStatus(ingress []networkingv1.IngressLoadBalancerIngress) []corev1.LoadBalancerIngress {
+	var converted []corev1.LoadBalancerIngress
+	for _, lb := range ingress {
+		converted = append(converted, corev1.LoadBalancerIngress{Hostname: lb.Hostname, IP: lb.IP})
+	}
+	return converted
+}
@@ -0,0 +1,28 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AutoscaleStatus returns the current and desired replicas of an app's HorizontalPodAutoscaler
+// Apps live in a namespace of the same name, and it returns an empty string for apps that don't autoscale
+func (c *Client) AutoscaleStatus(ctx context.Context, app string) (string, error) {
+	hpa, err := c.AutoscalingV2().HorizontalPodAutoscalers(app).Get(ctx, app, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var min int32 = 1
+	if hpa.Spec.MinReplicas != nil {
+		min = *hpa.Spec.MinReplicas
+	}
+
+	return fmt.Sprintf("%d/%d (%d-%d)", hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas, min, hpa.Spec.MaxReplicas), nil
+}
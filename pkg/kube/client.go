@@ -0,0 +1,34 @@
+package kube
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Client talks to the cluster ploy deploys apps to
+type Client struct {
+	kubernetes.Interface
+	Config *rest.Config
+}
+
+// NewClient creates a client for the cluster currently configured in your KUBECONFIG, the same one
+// the Pulumi Kubernetes provider deploys to
+func NewClient() (*Client, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading kubeconfig: %v", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating kubernetes client: %v", err)
+	}
+
+	return &Client{Interface: clientset, Config: config}, nil
+}
@@ -0,0 +1,113 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// ContainerName is the name ploy gives the app's container in every pod
+const ContainerName = "name"
+
+// ExitError is returned when a command run in the cluster exits unsuccessfully, so ploy can exit with the same code
+// Reason explains failures where the command never got to exit, like its pod being evicted
+type ExitError struct {
+	Code   int
+	Reason string
+}
+
+func (e *ExitError) Error() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// ExecOptions are the command to run in an app's pod and the streams to attach to it
+// With a TTY, stderr is merged into stdout, and Sizes reports the size of the local terminal
+type ExecOptions struct {
+	Command []string
+	TTY     bool
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Sizes   remotecommand.TerminalSizeQueue
+}
+
+// Exec runs a command in one of an app's running pods
+func (c *Client) Exec(ctx context.Context, app string, options ExecOptions) error {
+	pod, err := c.readyPod(ctx, app)
+	if err != nil {
+		return err
+	}
+
+	request := c.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(app).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: ContainerName,
+			Command:   options.Command,
+			Stdin:     options.Stdin != nil,
+			Stdout:    options.Stdout != nil,
+			Stderr:    options.Stderr != nil && !options.TTY,
+			TTY:       options.TTY,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.Config, "POST", request.URL())
+	if err != nil {
+		return fmt.Errorf("error connecting to pod %s: %v", pod, err)
+	}
+
+	streams := remotecommand.StreamOptions{
+		Stdin:             options.Stdin,
+		Stdout:            options.Stdout,
+		Tty:               options.TTY,
+		TerminalSizeQueue: options.Sizes,
+	}
+	if !options.TTY {
+		streams.Stderr = options.Stderr
+	}
+
+	err = executor.StreamWithContext(ctx, streams)
+	var exitErr exec.CodeExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitErr.Code}
+	}
+	return err
+}
+
+// readyPod picks a ready pod of the app, so commands don't land in a container that's crashing or still starting
+// Without one, the error lists the state of each pod instead
+func (c *Client) readyPod(ctx context.Context, app string) (string, error) {
+	pods, err := c.CoreV1().Pods(app).List(ctx, metav1.ListOptions{LabelSelector: Selector(app)})
+	if err != nil {
+		return "", fmt.Errorf("error listing pods of %s: %v", app, err)
+	}
+	if len(pods.Items) == 0 {
+		return "", fmt.Errorf("no pods found for %s", app)
+	}
+
+	var states []string
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil && pod.Status.Phase == corev1.PodRunning {
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+					return pod.Name, nil
+				}
+			}
+		}
+		status := podStatus(pod)
+		states = append(states, fmt.Sprintf("%s is %s with %s containers ready and %d restarts", status.Name, status.Status, status.Ready, status.Restarts))
+	}
+	return "", fmt.Errorf("no ready pods found for %s: %s", app, strings.Join(states, "; "))
+}
@@ -0,0 +1,178 @@
+package kube
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// LogOptions control which logs are read from an app's containers
+// Tail is the number of recent lines to start from, or all of them if it's negative
+type LogOptions struct {
+	Follow   bool
+	Since    time.Duration
+	Tail     int64
+	Previous bool
+}
+
+// LogLine is a single line logged by one of an app's containers
+type LogLine struct {
+	Time      time.Time `json:"time"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	Message   string    `json:"message"`
+}
+
+// Logs sends the logs of every container in an app's pods to lines, closing it once they've all been read
+// When following, pods that start during a rollout are picked up until the context is cancelled
+func (c *Client) Logs(ctx context.Context, app string, options LogOptions, lines chan<- LogLine) error {
+	defer close(lines)
+
+	logs := &logStreams{client: c, app: app, options: options, lines: lines, streamed: make(map[string]bool)}
+
+	if !options.Follow {
+		pods, err := c.CoreV1().Pods(app).List(ctx, metav1.ListOptions{LabelSelector: Selector(app)})
+		if err != nil {
+			return fmt.Errorf("error listing pods of %s: %v", app, err)
+		}
+		if len(pods.Items) == 0 {
+			return fmt.Errorf("no pods found for %s", app)
+		}
+		for i := range pods.Items {
+			logs.start(ctx, &pods.Items[i])
+		}
+		logs.wait.Wait()
+		return nil
+	}
+
+	// the watch starts with an event for every existing pod, then reports new and changed ones
+	// the API server closes watches after a while, so they're reopened until the context is cancelled
+	for {
+		watcher, err := c.CoreV1().Pods(app).Watch(ctx, metav1.ListOptions{LabelSelector: Selector(app)})
+		if err != nil {
+			logs.wait.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("error watching pods of %s: %v", app, err)
+		}
+
+		for event := range watcher.ResultChan() {
+			if event.Type != watch.Added && event.Type != watch.Modified {
+				continue
+			}
+			if pod, ok := event.Object.(*corev1.Pod); ok {
+				logs.start(ctx, pod)
+			}
+		}
+		watcher.Stop()
+
+		if ctx.Err() != nil {
+			logs.wait.Wait()
+			return nil
+		}
+	}
+}
+
+// logStreams tracks the containers whose logs have been streamed, so each is only streamed once
+type logStreams struct {
+	client  *Client
+	app     string
+	options LogOptions
+	lines   chan<- LogLine
+
+	wait     sync.WaitGroup
+	mutex    sync.Mutex
+	streamed map[string]bool
+}
+
+// start streams the logs of each of a pod's containers that has started and hasn't been streamed yet
+// Containers are told apart by their ID, so a restarted container is streamed again but a finished one isn't
+func (l *logStreams) start(ctx context.Context, pod *corev1.Pod) {
+	for _, status := range pod.Status.ContainerStatuses {
+		// previous logs only exist for containers that have restarted
+		started := status.State.Running != nil || status.State.Terminated != nil
+		if l.options.Previous {
+			started = status.RestartCount > 0
+		}
+		if !started {
+			continue
+		}
+
+		key := status.ContainerID
+		if l.options.Previous && status.LastTerminationState.Terminated != nil {
+			key = status.LastTerminationState.Terminated.ContainerID
+		}
+		if key == "" {
+			key = fmt.Sprintf("%s/%s/%d", pod.Name, status.Name, status.RestartCount)
+		}
+		l.mutex.Lock()
+		if l.streamed[key] {
+			l.mutex.Unlock()
+			continue
+		}
+		l.streamed[key] = true
+		l.mutex.Unlock()
+
+		l.wait.Add(1)
+		go func(pod string, container string) {
+			defer l.wait.Done()
+			if err := l.stream(ctx, pod, container); err != nil && ctx.Err() == nil {
+				l.lines <- LogLine{Time: time.Now(), Pod: pod, Container: container, Message: fmt.Sprintf("error reading logs: %v", err)}
+			}
+		}(pod.Name, status.Name)
+	}
+}
+
+// stream reads the logs of a single container line by line
+func (l *logStreams) stream(ctx context.Context, pod string, container string) error {
+	options := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     l.options.Follow,
+		Previous:   l.options.Previous,
+		Timestamps: true,
+	}
+	if l.options.Since > 0 {
+		seconds := int64(l.options.Since.Seconds())
+		options.SinceSeconds = &seconds
+	}
+	if l.options.Tail >= 0 {
+		tail := l.options.Tail
+		options.TailLines = &tail
+	}
+
+	stream, err := l.client.CoreV1().Pods(l.app).GetLogs(pod, options).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := LogLine{Pod: pod, Container: container, Message: scanner.Text()}
+
+		// each line starts with the time it was logged, as we asked for timestamps
+		if parts := strings.SplitN(line.Message, " ", 2); len(parts) == 2 {
+			if timestamp, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+				line.Time = timestamp
+				line.Message = parts[1]
+			}
+		}
+
+		select {
+		case l.lines <- line:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
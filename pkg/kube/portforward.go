@@ -0,0 +1,190 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardRetryInterval is how long to wait before connecting to another pod once a connection is lost
+const portForwardRetryInterval = time.Second
+
+// PrimaryPort returns the first port of the app's container, the one its probes and ingress use
+func (c *Client) PrimaryPort(ctx context.Context, app string) (int, error) {
+	return c.ContainerPort(ctx, app, "")
+}
+
+// ContainerPort resolves a port of the app's container from its name or number, or the primary port if it's empty
+func (c *Client) ContainerPort(ctx context.Context, app string, port string) (int, error) {
+	deployment, err := c.AppsV1().Deployments(app).Get(ctx, app, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return 0, fmt.Errorf("no deployment found for %s, has it been deployed to the current cluster?", app)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading deployment of %s: %v", app, err)
+	}
+
+	var ports []int
+	names := make(map[string]int)
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			ports = append(ports, int(containerPort.ContainerPort))
+			if containerPort.Name != "" {
+				names[containerPort.Name] = int(containerPort.ContainerPort)
+			}
+		}
+	}
+
+	if port == "" {
+		if len(ports) == 0 {
+			return 0, fmt.Errorf("%s doesn't expose any ports", app)
+		}
+		return ports[0], nil
+	}
+	if number, err := strconv.Atoi(port); err == nil {
+		return number, nil
+	}
+	if number, ok := names[port]; ok {
+		return number, nil
+	}
+	return 0, fmt.Errorf("%s has no port named %s", app, port)
+}
+
+// RemotePort resolves the pod port to forward to, like kubectl port-forward does for a Service
+// A Service port, by number or name, is mapped to its target port, otherwise it's a port of the app's container
+func (c *Client) RemotePort(ctx context.Context, app string, port string) (int, error) {
+	if port == "" {
+		return c.PrimaryPort(ctx, app)
+	}
+
+	service, err := c.CoreV1().Services(app).Get(ctx, app, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return 0, fmt.Errorf("error reading service of %s: %v", app, err)
+	}
+	if err == nil {
+		for _, servicePort := range service.Spec.Ports {
+			if port != servicePort.Name && port != strconv.Itoa(int(servicePort.Port)) {
+				continue
+			}
+			switch {
+			case servicePort.TargetPort.Type == intstr.String:
+				return c.ContainerPort(ctx, app, servicePort.TargetPort.StrVal)
+			case servicePort.TargetPort.IntVal != 0:
+				return int(servicePort.TargetPort.IntVal), nil
+			}
+			return int(servicePort.Port), nil
+		}
+	}
+
+	return c.ContainerPort(ctx, app, port)
+}
+
+// PortForward forwards a local port to a port of a ready pod of the app, through the Kubernetes API, until the
+// context is cancelled. Whenever the pod goes away, like during a rollout, it reconnects to another one
+// A local port of 0 picks a free one. connected is called with the pod and local port once it's listening, and lost
+// whenever the connection to a pod is lost, with the reason if there is one
+// If the first connection fails, like when the local port is in use or there's no ready pod, its error is returned
+func (c *Client) PortForward(ctx context.Context, app string, local int, remote int, connected func(pod string, local int), lost func(err error)) error {
+	established := false
+	for {
+		pod, err := c.readyPod(ctx, app)
+		if err == nil {
+			local, err = c.forward(ctx, app, pod, local, remote, func(pod string, local int) {
+				established = true
+				connected(pod, local)
+			})
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !established {
+			if err == nil {
+				return fmt.Errorf("connection to %s closed before it was ready", app)
+			}
+			return fmt.Errorf("error forwarding to %s: %v", app, err)
+		}
+		lost(err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(portForwardRetryInterval):
+		}
+	}
+}
+
+// forward forwards to a single pod until the connection is lost or the pod starts terminating
+// It returns the local port, so reconnecting keeps using the same one
+func (c *Client) forward(ctx context.Context, app string, pod string, local int, remote int, connected func(pod string, local int)) (int, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(c.Config)
+	if err != nil {
+		return local, err
+	}
+	url := c.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(app).
+		Name(pod).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+
+	stop := make(chan struct{})
+	readyChan := make(chan struct{})
+	forwarder, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", local, remote)}, stop, readyChan, io.Discard, io.Discard)
+	if err != nil {
+		return local, err
+	}
+
+	// stop forwarding when we're cancelled or the pod is going away
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		defer close(stop)
+		c.waitForPodGone(watchCtx, app, pod)
+	}()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- forwarder.ForwardPorts()
+	}()
+
+	select {
+	case <-readyChan:
+		if ports, err := forwarder.GetPorts(); err == nil && len(ports) > 0 {
+			local = int(ports[0].Local)
+		}
+		connected(pod, local)
+	case err := <-errs:
+		return local, err
+	}
+
+	return local, <-errs
+}
+
+// waitForPodGone returns once a pod is deleted or starts terminating, or the context is cancelled
+func (c *Client) waitForPodGone(ctx context.Context, namespace string, name string) {
+	ticker := time.NewTicker(rolloutPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pod, err := c.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) || (err == nil && pod.DeletionTimestamp != nil) {
+			return
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NameLabel is the label ploy puts on every resource of an app
+const NameLabel = "app.getploy.io/name"
+
+// rolloutPollInterval is how often the rollout is checked while waiting on it
+const rolloutPollInterval = 2 * time.Second
+
+// failedWaitingReasons are container states that won't fix themselves, so there's no point waiting
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// Selector selects all the pods of an app
+func Selector(app string) string {
+	return fmt.Sprintf("%s=%s", NameLabel, app)
+}
+
+// WaitForRollout waits until every replica of the app's Deployment is running the new pod template and ready
+// It gives up early if the rollout passes its progress deadline or new pods fail in a way they won't recover from
+func (c *Client) WaitForRollout(ctx context.Context, app string) error {
+	ticker := time.NewTicker(rolloutPollInterval)
+	defer ticker.Stop()
+
+	for {
+		done, err := c.rolloutStatus(ctx, app)
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for the rollout of %s", app)
+		case <-ticker.C:
+		}
+	}
+}
+
+// rolloutStatus checks whether the rollout has finished, returning an error if it has failed
+func (c *Client) rolloutStatus(ctx context.Context, app string) (bool, error) {
+	deployment, err := c.AppsV1().Deployments(app).Get(ctx, app, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, fmt.Errorf("rollout of %s exceeded its progress deadline: %s", app, condition.Message)
+		}
+	}
+
+	if err := c.failedPods(ctx, app, deployment); err != nil {
+		return false, err
+	}
+
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, nil
+	}
+
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+
+	// every replica is updated and available, and no old ones are left
+	return status.UpdatedReplicas == replicas && status.Replicas == replicas && status.AvailableReplicas == replicas, nil
+}
+
+// failedPods returns an error if any pod running the Deployment's current image has failed to start
+func (c *Client) failedPods(ctx context.Context, app string, deployment *appsv1.Deployment) error {
+	pods, err := c.CoreV1().Pods(app).List(ctx, metav1.ListOptions{LabelSelector: Selector(app)})
+	if err != nil {
+		return err
+	}
+
+	images := make(map[string]bool)
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		images[container.Image] = true
+	}
+
+	for _, pod := range pods.Items {
+		if len(pod.Spec.Containers) == 0 || !images[pod.Spec.Containers[0].Image] {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if waiting := status.State.Waiting; waiting != nil && failedWaitingReasons[waiting.Reason] {
+				return fmt.Errorf("pod %s is failing with %s: %s", pod.Name, waiting.Reason, waiting.Message)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Diagnose describes why an app's pods may not be healthy, with their warning events and recent container logs
+func (c *Client) Diagnose(ctx context.Context, app string) string {
+	pods, err := c.CoreV1().Pods(app).List(ctx, metav1.ListOptions{LabelSelector: Selector(app)})
+	if err != nil {
+		return fmt.Sprintf("unable to list pods: %v", err)
+	}
+
+	var out strings.Builder
+	for _, pod := range pods.Items {
+		fmt.Fprintf(&out, "Pod %s (%s)\n", pod.Name, pod.Status.Phase)
+
+		events, err := c.CoreV1().Events(app).List(ctx, metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("involvedObject.name=%s", pod.Name),
+		})
+		if err == nil {
+			for _, event := range events.Items {
+				if event.Type == corev1.EventTypeWarning {
+					fmt.Fprintf(&out, "  %s: %s\n", event.Reason, event.Message)
+				}
+			}
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			logs := c.recentLogs(ctx, pod, status)
+			if logs == "" {
+				continue
+			}
+			fmt.Fprintf(&out, "  Logs from %s:\n", status.Name)
+			for _, line := range strings.Split(strings.TrimRight(logs, "\n"), "\n") {
+				fmt.Fprintf(&out, "    %s\n", line)
+			}
+		}
+	}
+
+	return out.String()
+}
+
+// recentLogs returns the last lines logged by a container, from its previous run if it has restarted
+func (c *Client) recentLogs(ctx context.Context, pod corev1.Pod, status corev1.ContainerStatus) string {
+	var tail int64 = 20
+	options := &corev1.PodLogOptions{
+		Container: status.Name,
+		TailLines: &tail,
+		Previous:  status.RestartCount > 0 && status.State.Running == nil,
+	}
+
+	stream, err := c.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Stream(ctx)
+	if err != nil {
+		return ""
+	}
+	defer stream.Close()
+
+	var logs bytes.Buffer
+	if _, err := io.Copy(&logs, stream); err != nil {
+		return ""
+	}
+	return logs.String()
+}
@@ -0,0 +1,271 @@
+package kube
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JobLabel is put on the pods of jobs instead of NameLabel, so the app's Service never routes traffic to them
+// Its value is the kind of job, one of the JobRun or JobRelease kinds
+const JobLabel = "app.getploy.io/job"
+
+// Kinds of job ploy runs alongside an app
+const (
+	JobRun     = "run"
+	JobRelease = "release"
+)
+
+// jobPollInterval is how often a job is checked while waiting on it
+const jobPollInterval = 2 * time.Second
+
+// jobLogsGracePeriod is how long logs of finished jobs are still read for once we've stopped following them
+const jobLogsGracePeriod = 5 * time.Second
+
+// RunOptions describe a one-off job to run with an app's image and environment
+type RunOptions struct {
+	Command []string
+	// TTL is how long the finished job is kept around before Kubernetes deletes it
+	TTL time.Duration
+	// Output receives the job's logs as they're written
+	Output io.Writer
+}
+
+// Run launches a Job with the same image, environment and secrets as the app's running Deployment, and
+// streams its logs until it finishes, returning an ExitError if the command fails
+func (c *Client) Run(ctx context.Context, app string, options RunOptions) error {
+	deployment, err := c.AppsV1().Deployments(app).Get(ctx, app, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("no deployment found for %s, has it been deployed to the current cluster?", app)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading deployment of %s: %v", app, err)
+	}
+	template := deployment.Spec.Template.Spec
+	if len(template.Containers) == 0 {
+		return fmt.Errorf("deployment of %s has no containers", app)
+	}
+	appContainer := template.Containers[0]
+
+	job, err := c.CreateJob(ctx, app, JobRun, corev1.Container{
+		Name:      ContainerName,
+		Image:     appContainer.Image,
+		Command:   options.Command,
+		Env:       appContainer.Env,
+		EnvFrom:   appContainer.EnvFrom,
+		Resources: appContainer.Resources,
+	}, template.ImagePullSecrets, options.TTL)
+	if err != nil {
+		return err
+	}
+
+	return c.WaitForJob(ctx, app, job, options.Output)
+}
+
+// CreateJob creates a Job of a kind in an app's namespace that runs a container once, without retrying it
+// The Job carries the app's name label, and is deleted by Kubernetes once the TTL has passed after it finishes
+func (c *Client) CreateJob(ctx context.Context, app string, kind string, container corev1.Container, pullSecrets []corev1.LocalObjectReference, ttl time.Duration) (string, error) {
+	var backoffLimit int32 = 0
+	ttlSeconds := int32(ttl.Seconds())
+
+	job, err := c.BatchV1().Jobs(app).Create(ctx, &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-%s-", app, kind),
+			Namespace:    app,
+			Labels:       map[string]string{NameLabel: app},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttlSeconds,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{JobLabel: kind},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: pullSecrets,
+					Containers:       []corev1.Container{container},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error creating job for %s: %v", app, err)
+	}
+
+	return job.Name, nil
+}
+
+// WaitForJob streams the logs of a job's pod to output until it finishes, returning an ExitError if it failed
+// A pod or job that fails without its container exiting, like an eviction, gives an ExitError with the reason
+func (c *Client) WaitForJob(ctx context.Context, app string, job string, output io.Writer) error {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	streamed := false
+	for {
+		pod, err := c.jobPod(ctx, app, job)
+		if err != nil {
+			return err
+		}
+
+		if pod != nil {
+			for _, status := range pod.Status.ContainerStatuses {
+				if waiting := status.State.Waiting; waiting != nil && failedWaitingReasons[waiting.Reason] {
+					return fmt.Errorf("job %s is failing with %s: %s", job, waiting.Reason, waiting.Message)
+				}
+				if !streamed && (status.State.Running != nil || status.State.Terminated != nil) {
+					// following the logs only returns once the container exits
+					streamed = true
+					if err := c.copyLogs(ctx, app, pod.Name, output); err != nil {
+						return fmt.Errorf("error reading logs of job %s: %v", job, err)
+					}
+				}
+				if terminated := status.State.Terminated; terminated != nil {
+					if terminated.ExitCode != 0 {
+						return &ExitError{Code: int(terminated.ExitCode)}
+					}
+					return nil
+				}
+			}
+
+			// evicted pods, and ones that never started, fail without their container terminating
+			if pod.Status.Phase == corev1.PodFailed {
+				return &ExitError{Code: 1, Reason: fmt.Sprintf("pod %s of job %s failed: %s %s", pod.Name, job, pod.Status.Reason, pod.Status.Message)}
+			}
+		}
+
+		// the job also gives up on pods that are never scheduled, once its deadline passes
+		status, err := c.BatchV1().Jobs(app).Get(ctx, job, metav1.GetOptions{})
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("error reading job %s: %v", job, err)
+		}
+		if err == nil {
+			for _, condition := range status.Status.Conditions {
+				if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+					return &ExitError{Code: 1, Reason: fmt.Sprintf("job %s failed: %s %s", job, condition.Reason, condition.Message)}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for job %s, it's still running", job)
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobPod returns the pod a job created, or nil if it hasn't been created yet
+func (c *Client) jobPod(ctx context.Context, app string, job string) (*corev1.Pod, error) {
+	pods, err := c.CoreV1().Pods(app).List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", job)})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods of job %s: %v", job, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, nil
+	}
+	return &pods.Items[0], nil
+}
+
+// FollowJobs streams the logs of every job of a kind started in an app's namespace since a time, until the context is
+// cancelled, passing each line to write
+// Logs still being read then get a short grace period, as the job usually only just finished
+func (c *Client) FollowJobs(ctx context.Context, app string, kind string, since time.Time, write func(pod string, line string)) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var streams sync.WaitGroup
+	streaming := make(map[string]bool)
+	since = since.Truncate(time.Second)
+
+	for {
+		pods, err := c.CoreV1().Pods(app).List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", JobLabel, kind)})
+		if err == nil {
+			for _, pod := range pods.Items {
+				if streaming[pod.Name] || pod.CreationTimestamp.Time.Before(since) || !started(pod) {
+					continue
+				}
+				streaming[pod.Name] = true
+
+				streams.Add(1)
+				go func(pod string) {
+					defer streams.Done()
+					if err := c.followLogs(streamCtx, app, pod, func(line string) { write(pod, line) }); err != nil && streamCtx.Err() == nil {
+						write(pod, fmt.Sprintf("error reading logs: %v", err))
+					}
+				}(pod.Name)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			done := make(chan struct{})
+			go func() {
+				streams.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(jobLogsGracePeriod):
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// started reports whether any of a pod's containers has started, so it has logs to read
+func started(pod corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running != nil || status.State.Terminated != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// followLogs reads the logs of a pod's container line by line until it exits
+func (c *Client) followLogs(ctx context.Context, namespace string, pod string, write func(line string)) error {
+	stream, err := c.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: ContainerName,
+		Follow:    true,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		write(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// copyLogs follows the logs of a pod's container until it exits
+func (c *Client) copyLogs(ctx context.Context, namespace string, pod string, output io.Writer) error {
+	stream, err := c.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: ContainerName,
+		Follow:    true,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(output, stream)
+	return err
+}
@@ -0,0 +1,152 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxStatusEvents is how many of the most recent warning events are reported
+const maxStatusEvents = 10
+
+// AppStatus is the live state of an app's Deployment and pods
+type AppStatus struct {
+	Desired   int32
+	Ready     int32
+	Updated   int32
+	Available int32
+	Image     string
+	Pods      []PodStatus
+	Events    []WarningEvent
+}
+
+// PodStatus is the health of a single pod
+// Status is the pod's phase, or why its containers aren't running, like kubectl shows it
+type PodStatus struct {
+	Name     string
+	Status   string
+	Ready    string
+	Restarts int32
+	Node     string
+	Image    string
+	Created  time.Time
+}
+
+// WarningEvent is a warning reported for one of an app's resources
+type WarningEvent struct {
+	Time    time.Time
+	Object  string
+	Reason  string
+	Message string
+	Count   int32
+}
+
+// Status reads the live state of an app from its namespace
+func (c *Client) Status(ctx context.Context, app string) (*AppStatus, error) {
+	deployment, err := c.AppsV1().Deployments(app).Get(ctx, app, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("no deployment found for %s, has it been deployed to the current cluster?", app)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading deployment of %s: %v", app, err)
+	}
+
+	status := &AppStatus{
+		Desired:   1,
+		Ready:     deployment.Status.ReadyReplicas,
+		Updated:   deployment.Status.UpdatedReplicas,
+		Available: deployment.Status.AvailableReplicas,
+	}
+	if deployment.Spec.Replicas != nil {
+		status.Desired = *deployment.Spec.Replicas
+	}
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		status.Image = containers[0].Image
+	}
+
+	pods, err := c.CoreV1().Pods(app).List(ctx, metav1.ListOptions{LabelSelector: Selector(app)})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods of %s: %v", app, err)
+	}
+	for _, pod := range pods.Items {
+		status.Pods = append(status.Pods, podStatus(pod))
+	}
+	sort.Slice(status.Pods, func(i, j int) bool {
+		return status.Pods[i].Created.After(status.Pods[j].Created)
+	})
+
+	events, err := c.CoreV1().Events(app).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("type=%s", corev1.EventTypeWarning),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing events of %s: %v", app, err)
+	}
+	for _, event := range events.Items {
+		status.Events = append(status.Events, WarningEvent{
+			Time:    eventTime(event),
+			Object:  fmt.Sprintf("%s/%s", event.InvolvedObject.Kind, event.InvolvedObject.Name),
+			Reason:  event.Reason,
+			Message: event.Message,
+			Count:   event.Count,
+		})
+	}
+	sort.Slice(status.Events, func(i, j int) bool {
+		return status.Events[i].Time.After(status.Events[j].Time)
+	})
+	if len(status.Events) > maxStatusEvents {
+		status.Events = status.Events[:maxStatusEvents]
+	}
+
+	return status, nil
+}
+
+// podStatus summarises a pod, surfacing reasons like CrashLoopBackOff over its phase
+func podStatus(pod corev1.Pod) PodStatus {
+	status := PodStatus{
+		Name:    pod.Name,
+		Status:  string(pod.Status.Phase),
+		Node:    pod.Spec.NodeName,
+		Created: pod.CreationTimestamp.Time,
+	}
+	if pod.Status.Reason != "" {
+		status.Status = pod.Status.Reason
+	}
+	if len(pod.Spec.Containers) > 0 {
+		status.Image = pod.Spec.Containers[0].Image
+	}
+
+	var ready int
+	for _, container := range pod.Status.ContainerStatuses {
+		status.Restarts += container.RestartCount
+		if container.Ready {
+			ready++
+		}
+		if waiting := container.State.Waiting; waiting != nil && waiting.Reason != "" {
+			status.Status = waiting.Reason
+		} else if terminated := container.State.Terminated; terminated != nil && terminated.Reason != "" {
+			status.Status = terminated.Reason
+		}
+	}
+	if pod.DeletionTimestamp != nil {
+		status.Status = "Terminating"
+	}
+	status.Ready = fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers))
+
+	return status
+}
+
+// eventTime returns when an event was last seen, whichever API populated it
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
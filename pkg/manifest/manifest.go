@@ -0,0 +1,524 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the manifest ploy looks for in the docker context
+const FileName = "ploy.yaml"
+
+var (
+	appName   = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	yamlError = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+)
+
+// Manifest declares how an app is built and deployed, so deploys are reproducible
+type Manifest struct {
+	Name      string            `yaml:"name"`
+	Ports     []Port            `yaml:"ports"`
+	Replicas  *int              `yaml:"replicas"`
+	Autoscale *Autoscale        `yaml:"autoscale"`
+	Env       map[string]string `yaml:"env"`
+	Resources Resources         `yaml:"resources"`
+	Probes    Probes            `yaml:"probes"`
+	Service   Service           `yaml:"service"`
+	Build     Build             `yaml:"build"`
+	Release   Command           `yaml:"release"`
+
+	path     string
+	dir      string
+	root     *yaml.Node
+	registry *pulumi.Registry
+}
+
+// Port is either a port specification string, as accepted by --port, or a mapping of its fields
+type Port struct {
+	pulumi.Port
+	spec string
+}
+
+// Command is either a string run with the image's shell, or a list of arguments run as they are
+type Command []string
+
+// Resources are the compute requests and limits for each pod
+type Resources struct {
+	Requests Quantities `yaml:"requests"`
+	Limits   Quantities `yaml:"limits"`
+}
+
+// Quantities are CPU and memory amounts in Kubernetes notation
+type Quantities struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+// Autoscale configures a HorizontalPodAutoscaler in place of a fixed replica count
+type Autoscale struct {
+	Min     int      `yaml:"min"`
+	Max     int      `yaml:"max"`
+	CPU     int      `yaml:"cpu"`
+	Memory  int      `yaml:"memory"`
+	Metrics []Metric `yaml:"metrics"`
+}
+
+// Metric is a custom per-pod metric and its target average value
+type Metric struct {
+	Name   string `yaml:"name"`
+	Target string `yaml:"target"`
+}
+
+func (a *Autoscale) autoscale() *pulumi.Autoscale {
+	if a == nil {
+		return nil
+	}
+
+	autoscale := &pulumi.Autoscale{Min: a.Min, Max: a.Max, CPU: a.CPU, Memory: a.Memory}
+	for _, metric := range a.Metrics {
+		autoscale.Metrics = append(autoscale.Metrics, pulumi.Metric(metric))
+	}
+	return autoscale
+}
+
+// Probes are the health checks for the app container
+type Probes struct {
+	Liveness  *Probe `yaml:"liveness"`
+	Readiness *Probe `yaml:"readiness"`
+	Startup   *Probe `yaml:"startup"`
+}
+
+// Probe is a single health check, its type is one of http, tcp, grpc, exec or none
+type Probe struct {
+	Type    string   `yaml:"type"`
+	Path    string   `yaml:"path"`
+	Port    int      `yaml:"port"`
+	Service string   `yaml:"service"`
+	Command []string `yaml:"command"`
+
+	InitialDelaySeconds int `yaml:"initialDelaySeconds"`
+	PeriodSeconds       int `yaml:"periodSeconds"`
+	TimeoutSeconds      int `yaml:"timeoutSeconds"`
+	SuccessThreshold    int `yaml:"successThreshold"`
+	FailureThreshold    int `yaml:"failureThreshold"`
+}
+
+func (p *Probe) probe() *pulumi.Probe {
+	if p == nil {
+		return nil
+	}
+
+	probe := pulumi.Probe(*p)
+	return &probe
+}
+
+// Service configures how the app is exposed
+type Service struct {
+	Type         string   `yaml:"type"`
+	Expose       string   `yaml:"expose"`
+	SourceRanges []string `yaml:"sourceRanges"`
+	Ingress      *Ingress `yaml:"ingress"`
+}
+
+// Ingress configures the routing used when the service type is ingress
+type Ingress struct {
+	Host  string `yaml:"host"`
+	Path  string `yaml:"path"`
+	Class string `yaml:"class"`
+}
+
+// Build configures how the app's image is built
+type Build struct {
+	Builder           string `yaml:"builder"`
+	BuildpacksBuilder string `yaml:"buildpacksBuilder"`
+
+	Context    string            `yaml:"context"`
+	Registry   string            `yaml:"registry"`
+	Dockerfile string            `yaml:"dockerfile"`
+	Target     string            `yaml:"target"`
+	Args       map[string]string `yaml:"args"`
+	SecretArgs []string          `yaml:"secretArgs"`
+	CacheFrom  []string          `yaml:"cacheFrom"`
+	Platform   string            `yaml:"platform"`
+}
+
+// UnmarshalYAML accepts a port as either a specification string or a mapping
+func (p *Port) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.spec = node.Value
+		return nil
+	}
+
+	var fields struct {
+		Name        string `yaml:"name"`
+		Port        int    `yaml:"port"`
+		ServicePort int    `yaml:"servicePort"`
+		Protocol    string `yaml:"protocol"`
+	}
+	if err := node.Decode(&fields); err != nil {
+		return err
+	}
+
+	spec := strconv.Itoa(fields.Port)
+	if fields.ServicePort != 0 {
+		spec = fmt.Sprintf("%d:%s", fields.ServicePort, spec)
+	}
+	if fields.Name != "" {
+		spec = fmt.Sprintf("%s=%s", fields.Name, spec)
+	}
+	if fields.Protocol != "" {
+		spec = fmt.Sprintf("%s/%s", spec, fields.Protocol)
+	}
+	p.spec = spec
+
+	return nil
+}
+
+// UnmarshalYAML accepts a command as either a shell string or a list of arguments
+func (c *Command) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if strings.TrimSpace(node.Value) == "" {
+			*c = Command{}
+			return nil
+		}
+		*c = Command{"/bin/sh", "-c", node.Value}
+		return nil
+	}
+
+	var args []string
+	if err := node.Decode(&args); err != nil {
+		return err
+	}
+	*c = args
+	return nil
+}
+
+// Error is a problem found in a manifest, with the line it was found on
+type Error struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+}
+
+// Errors is every problem found in a manifest
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Load reads the manifest from a directory, returning nil if there isn't one
+func Load(directory string) (*Manifest, error) {
+	path := filepath.Join(directory, FileName)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	return Parse(path, data)
+}
+
+// Parse decodes and validates a manifest
+func Parse(path string, data []byte) (*Manifest, error) {
+	m := &Manifest{path: path, dir: filepath.Dir(path)}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, decodeError(path, err)
+	}
+	if len(root.Content) == 0 {
+		return m, nil
+	}
+	m.root = root.Content[0]
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(m); err != nil {
+		return nil, decodeError(path, err)
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// decodeError converts the errors from the yaml decoder to the same form as validation errors
+func decodeError(path string, err error) error {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	var errs Errors
+	for _, message := range messages {
+		var line int
+		if match := yamlError.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = message[len(match[0]):]
+		}
+		errs = append(errs, Error{Path: path, Line: line, Message: message})
+	}
+
+	return errs
+}
+
+// validate checks every value in the manifest, reporting all the problems at once
+func (m *Manifest) validate() error {
+	var errs Errors
+	add := func(err error, path ...interface{}) {
+		errs = append(errs, Error{Path: m.path, Line: m.line(path...), Message: err.Error()})
+	}
+
+	if m.Name != "" && (len(m.Name) > 63 || !appName.MatchString(m.Name)) {
+		add(fmt.Errorf("name %s must be no more than 63 lowercase alphanumeric characters or '-'", m.Name), "name")
+	}
+
+	ports := make([]pulumi.Port, 0, len(m.Ports))
+	for i := range m.Ports {
+		port, err := pulumi.ParsePort(m.Ports[i].spec)
+		if err != nil {
+			add(err, "ports", i)
+			continue
+		}
+		m.Ports[i].Port = port
+		ports = append(ports, port)
+	}
+	if len(ports) == len(m.Ports) {
+		if err := pulumi.ValidatePorts(ports); err != nil {
+			add(err, "ports")
+		}
+	}
+
+	if m.Replicas != nil && *m.Replicas < 0 {
+		add(fmt.Errorf("replicas must not be negative, got %d", *m.Replicas), "replicas")
+	}
+
+	if err := m.Autoscale.autoscale().Validate(); err != nil {
+		add(err, "autoscale")
+	}
+
+	for key := range m.Env {
+		if err := pulumi.ValidateEnvName(key); err != nil {
+			add(err, "env", key)
+		}
+	}
+
+	if err := m.resources().Validate(); err != nil {
+		add(err, "resources")
+	}
+
+	// each probe is checked on its own so problems point at the right line
+	probes := []struct {
+		kind   string
+		probes pulumi.Probes
+	}{
+		{"liveness", pulumi.Probes{Liveness: m.Probes.Liveness.probe()}},
+		{"readiness", pulumi.Probes{Readiness: m.Probes.Readiness.probe()}},
+		{"startup", pulumi.Probes{Startup: m.Probes.Startup.probe()}},
+	}
+	for _, probe := range probes {
+		if err := probe.probes.Validate(); err != nil {
+			add(err, "probes", probe.kind)
+		}
+	}
+
+	switch m.Service.Type {
+	case "", "loadbalancer", "nlb":
+		if m.Service.Ingress != nil {
+			add(fmt.Errorf("ingress settings need the service type to be ingress"), "service", "ingress")
+		}
+	case "ingress":
+		if err := m.ingress().Validate(); err != nil {
+			add(err, "service", "ingress")
+		}
+	default:
+		add(fmt.Errorf("unknown service type %s, must be loadbalancer, nlb or ingress", m.Service.Type), "service", "type")
+	}
+
+	if err := pulumi.ValidateExposure(m.Service.Expose, m.Service.SourceRanges, m.ingress()); err != nil {
+		add(err, "service")
+	}
+
+	if m.Build.Context != "" {
+		if info, err := os.Stat(m.context()); err != nil || !info.IsDir() {
+			add(fmt.Errorf("build context %s is not a directory", m.Build.Context), "build", "context")
+		}
+	}
+
+	for key := range m.Build.Args {
+		if err := pulumi.ValidateEnvName(key); err != nil {
+			add(err, "build", "args", key)
+		}
+	}
+	for i, name := range m.Build.SecretArgs {
+		if err := pulumi.ValidateEnvName(name); err != nil {
+			add(err, "build", "secretArgs", i)
+		}
+	}
+
+	// secret build args are only checked when building, as they come from the environment
+	build := m.build()
+	build.SecretArgs = nil
+	if err := build.Validate(); err != nil {
+		add(err, "build")
+	}
+
+	if m.Build.Dockerfile != "" {
+		if info, err := os.Stat(m.build().Dockerfile); err != nil || info.IsDir() {
+			add(fmt.Errorf("dockerfile %s is not a file", m.Build.Dockerfile), "build", "dockerfile")
+		}
+	}
+
+	if m.Build.Registry != "" {
+		registry, err := pulumi.ParseRegistry(m.Build.Registry)
+		if err != nil {
+			add(err, "build", "registry")
+		}
+		m.registry = registry
+	}
+
+	if m.Release != nil && len(m.Release) == 0 {
+		add(fmt.Errorf("release command must not be empty"), "release")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// line finds the line of a value in the manifest from its path of mapping keys and sequence indexes
+// It returns the line of the deepest value found, so missing values point at their parent
+func (m *Manifest) line(path ...interface{}) int {
+	node := m.root
+	if node == nil {
+		return 0
+	}
+
+	for _, element := range path {
+		var next *yaml.Node
+		switch key := element.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == key {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+				next = node.Content[key]
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+
+	return node.Line
+}
+
+func (m *Manifest) resources() pulumi.Resources {
+	return pulumi.Resources{
+		CPURequest:    m.Resources.Requests.CPU,
+		CPULimit:      m.Resources.Limits.CPU,
+		MemoryRequest: m.Resources.Requests.Memory,
+		MemoryLimit:   m.Resources.Limits.Memory,
+	}
+}
+
+// ingress returns the ingress settings when the service type is ingress
+func (m *Manifest) ingress() *pulumi.Ingress {
+	if m.Service.Type != "ingress" {
+		return nil
+	}
+	if m.Service.Ingress == nil {
+		return &pulumi.Ingress{}
+	}
+	ingress := pulumi.Ingress(*m.Service.Ingress)
+	return &ingress
+}
+
+// context returns the build context relative to the manifest's directory
+func (m *Manifest) context() string {
+	if m.Build.Context == "" {
+		return m.dir
+	}
+	if filepath.IsAbs(m.Build.Context) {
+		return m.Build.Context
+	}
+	return filepath.Join(m.dir, m.Build.Context)
+}
+
+// build returns the build settings, with the Dockerfile relative to the manifest's directory
+func (m *Manifest) build() pulumi.Build {
+	build := pulumi.Build{
+		Builder:           m.Build.Builder,
+		BuildpacksBuilder: m.Build.BuildpacksBuilder,
+		Dockerfile:        m.Build.Dockerfile,
+		Target:            m.Build.Target,
+		Args:              m.Build.Args,
+		SecretArgs:        m.Build.SecretArgs,
+		CacheFrom:         m.Build.CacheFrom,
+		Platform:          m.Build.Platform,
+	}
+	if build.Dockerfile != "" && !filepath.IsAbs(build.Dockerfile) {
+		build.Dockerfile = filepath.Join(m.dir, build.Dockerfile)
+	}
+	return build
+}
+
+// DeploymentArgs converts the manifest into deployment settings, which flags can then override
+func (m *Manifest) DeploymentArgs() *pulumi.PloyDeploymentArgs {
+	args := &pulumi.PloyDeploymentArgs{
+		Directory:    m.context(),
+		Nlb:          m.Service.Type == "nlb",
+		Expose:       m.Service.Expose,
+		SourceRanges: m.Service.SourceRanges,
+		Ingress:      m.ingress(),
+		Replicas:     m.Replicas,
+		Autoscale:    m.Autoscale.autoscale(),
+		Resources:    m.resources(),
+		Probes: pulumi.Probes{
+			Liveness:  m.Probes.Liveness.probe(),
+			Readiness: m.Probes.Readiness.probe(),
+			Startup:   m.Probes.Startup.probe(),
+		},
+		Env:            m.Env,
+		Registry:       m.registry,
+		Build:          m.build(),
+		ReleaseCommand: m.Release,
+	}
+
+	for _, port := range m.Ports {
+		args.Ports = append(args.Ports, port.Port)
+	}
+
+	return args
+}
@@ -0,0 +1,147 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+)
+
+func TestParse(t *testing.T) {
+	data := `name: my-app
+ports:
+  - http=80:8080/http
+  - name: grpc
+    port: 50051
+    protocol: grpc
+replicas: 2
+env:
+  LOG_LEVEL: debug
+service:
+  type: nlb
+  expose: private
+release: ./migrate up
+`
+	m, err := Parse("ploy.yaml", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	args := m.DeploymentArgs()
+	wantPorts := []pulumi.Port{
+		{Name: "http", Port: 8080, ServicePort: 80, Protocol: "TCP", AppProtocol: "http"},
+		{Name: "grpc", Port: 50051, ServicePort: 50051, Protocol: "TCP", AppProtocol: "grpc"},
+	}
+	if !reflect.DeepEqual(args.Ports, wantPorts) {
+		t.Errorf("ports = %+v, want %+v", args.Ports, wantPorts)
+	}
+	if args.Replicas == nil || *args.Replicas != 2 {
+		t.Errorf("replicas = %v, want 2", args.Replicas)
+	}
+	if !args.Nlb || args.Expose != pulumi.ExposePrivate {
+		t.Errorf("nlb = %v, expose = %s, want an nlb exposed privately", args.Nlb, args.Expose)
+	}
+	if want := []string{"/bin/sh", "-c", "./migrate up"}; !reflect.DeepEqual(args.ReleaseCommand, want) {
+		t.Errorf("release command = %q, want %q", args.ReleaseCommand, want)
+	}
+}
+
+func TestParseReleaseCommandList(t *testing.T) {
+	m, err := Parse("ploy.yaml", []byte("release: [\"./migrate\", \"up\"]\n"))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if want := []string{"./migrate", "up"}; !reflect.DeepEqual([]string(m.Release), want) {
+		t.Errorf("release command = %q, want %q", m.Release, want)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	m, err := Parse("ploy.yaml", nil)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if m.Name != "" || len(m.Ports) != 0 {
+		t.Errorf("Parse of an empty manifest = %+v, want no settings", m)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "syntax error",
+			data: "name: my-app\n\tports: []\n",
+			want: []string{"ploy.yaml:2: found a tab character"},
+		},
+		{
+			name: "unknown field",
+			data: "name: my-app\nreplica: 2\n",
+			want: []string{"ploy.yaml:2: field replica not found"},
+		},
+		{
+			name: "wrong type",
+			data: "name: my-app\nreplicas: two\n",
+			want: []string{"ploy.yaml:2: cannot unmarshal"},
+		},
+		{
+			name: "invalid name",
+			data: "name: My_App\n",
+			want: []string{"ploy.yaml:1: name My_App must be"},
+		},
+		{
+			name: "unknown protocol",
+			data: "ports:\n  - 8080\n  - 9090/tpc\n",
+			want: []string{"ploy.yaml:3: invalid port \"9090/tpc\": unknown protocol tpc"},
+		},
+		{
+			name: "duplicate ports",
+			data: "ports:\n  - 8080\n  - 8080\n",
+			want: []string{"ploy.yaml:2: port name tcp-8080 is used more than once"},
+		},
+		{
+			name: "unknown service type",
+			data: "service:\n  type: nodeport\n",
+			want: []string{"ploy.yaml:2: unknown service type nodeport"},
+		},
+		{
+			name: "empty release command",
+			data: "release: []\n",
+			want: []string{"ploy.yaml:1: release command must not be empty"},
+		},
+		{
+			name: "every problem at once",
+			data: "name: My_App\nreplicas: -1\nenv:\n  1BAD: x\n",
+			want: []string{
+				"ploy.yaml:1: name My_App must be",
+				"ploy.yaml:2: replicas must not be negative",
+				"ploy.yaml:4: ",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse("ploy.yaml", []byte(test.data))
+			if err == nil {
+				t.Fatalf("Parse returned no error, want %q", test.want)
+			}
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("Parse error is a %T, want Errors", err)
+			}
+			if len(errs) != len(test.want) {
+				t.Fatalf("Parse returned %d errors, want %d:\n%v", len(errs), len(test.want), err)
+			}
+			for i, want := range test.want {
+				if !strings.HasPrefix(errs[i].Error(), want) {
+					t.Errorf("error %d = %q, want it to start with %q", i, errs[i].Error(), want)
+				}
+			}
+		})
+	}
+}
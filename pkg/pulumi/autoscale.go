@@ -0,0 +1,145 @@
+package pulumi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	autoscalingv2 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/autoscaling/v2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Autoscale configures a HorizontalPodAutoscaler for the app
+// CPU and Memory are target average utilizations as a percentage of the pods' requests
+type Autoscale struct {
+	Min     int
+	Max     int
+	CPU     int
+	Memory  int
+	Metrics []Metric
+}
+
+// Metric is a custom per-pod metric, such as requests per second, and the average value to scale on
+type Metric struct {
+	Name   string
+	Target string
+}
+
+// ParseAutoscale parses an autoscaling specification of the form min=2,max=10,cpu=70
+// memory sets a memory utilization target, and metric:<name> a custom per-pod metric, any other key is rejected
+// so a typo doesn't scale on a metric that doesn't exist
+func ParseAutoscale(spec string) (*Autoscale, error) {
+	autoscale := &Autoscale{}
+
+	for _, field := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid autoscaling setting %q: must be of the form key=value", field)
+		}
+		key, value := parts[0], parts[1]
+
+		var target *int
+		switch key {
+		case "min":
+			target = &autoscale.Min
+		case "max":
+			target = &autoscale.Max
+		case "cpu":
+			target = &autoscale.CPU
+		case "memory":
+			target = &autoscale.Memory
+		default:
+			if name := strings.TrimPrefix(key, "metric:"); name != key && name != "" {
+				autoscale.Metrics = append(autoscale.Metrics, Metric{Name: name, Target: value})
+				continue
+			}
+			return nil, fmt.Errorf("unknown autoscaling setting %s: must be min, max, cpu, memory or metric:<name> for a custom metric", key)
+		}
+
+		number, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid autoscaling %s %q: %v", key, value, err)
+		}
+		*target = number
+	}
+
+	return autoscale, nil
+}
+
+// Validate checks the replica bounds make sense and that there's something to scale on
+func (a *Autoscale) Validate() error {
+	if a == nil {
+		return nil
+	}
+
+	if a.Min < 1 {
+		return fmt.Errorf("autoscaling min must be at least 1, got %d", a.Min)
+	}
+	if a.Max < a.Min {
+		return fmt.Errorf("autoscaling max %d must not be less than min %d", a.Max, a.Min)
+	}
+	if a.CPU < 0 || a.Memory < 0 {
+		return fmt.Errorf("autoscaling utilization targets must not be negative")
+	}
+	if a.CPU == 0 && a.Memory == 0 && len(a.Metrics) == 0 {
+		return fmt.Errorf("autoscaling needs a cpu, memory or custom metric target")
+	}
+
+	for _, metric := range a.Metrics {
+		quantity, err := resource.ParseQuantity(metric.Target)
+		if err != nil {
+			return fmt.Errorf("invalid target %q for metric %s: %v", metric.Target, metric.Name, err)
+		}
+		if quantity.Sign() <= 0 {
+			return fmt.Errorf("target for metric %s must be greater than zero", metric.Name)
+		}
+	}
+
+	return nil
+}
+
+// metricSpecs converts the targets into the HorizontalPodAutoscaler's metrics
+func (a *Autoscale) metricSpecs() autoscalingv2.MetricSpecArray {
+	metrics := autoscalingv2.MetricSpecArray{}
+
+	utilizations := []struct {
+		name   string
+		target int
+	}{
+		{"cpu", a.CPU},
+		{"memory", a.Memory},
+	}
+	for _, utilization := range utilizations {
+		if utilization.target == 0 {
+			continue
+		}
+		metrics = append(metrics, autoscalingv2.MetricSpecArgs{
+			Type: pulumi.String("Resource"),
+			Resource: &autoscalingv2.ResourceMetricSourceArgs{
+				Name: pulumi.String(utilization.name),
+				Target: autoscalingv2.MetricTargetArgs{
+					Type:               pulumi.String("Utilization"),
+					AverageUtilization: pulumi.Int(utilization.target),
+				},
+			},
+		})
+	}
+
+	for _, metric := range a.Metrics {
+		metrics = append(metrics, autoscalingv2.MetricSpecArgs{
+			Type: pulumi.String("Pods"),
+			Pods: &autoscalingv2.PodsMetricSourceArgs{
+				Metric: autoscalingv2.MetricIdentifierArgs{
+					Name: pulumi.String(metric.Name),
+				},
+				Target: autoscalingv2.MetricTargetArgs{
+					Type:         pulumi.String("AverageValue"),
+					AverageValue: pulumi.String(metric.Target),
+				},
+			},
+		})
+	}
+
+	return metrics
+}
@@ -0,0 +1,84 @@
+package pulumi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAutoscale(t *testing.T) {
+	tests := []struct {
+		spec string
+		want *Autoscale
+		err  string
+	}{
+		{spec: "min=2,max=10,cpu=70", want: &Autoscale{Min: 2, Max: 10, CPU: 70}},
+		{spec: "min=1, max=3, memory=80%", want: &Autoscale{Min: 1, Max: 3, Memory: 80}},
+		{
+			spec: "min=2,max=10,metric:http_requests_per_second=100",
+			want: &Autoscale{Min: 2, Max: 10, Metrics: []Metric{{Name: "http_requests_per_second", Target: "100"}}},
+		},
+		{
+			spec: "min=2,max=10,cpu=50,metric:queue_depth=500m",
+			want: &Autoscale{Min: 2, Max: 10, CPU: 50, Metrics: []Metric{{Name: "queue_depth", Target: "500m"}}},
+		},
+		{spec: "min=2,max=10,cpux=70", err: "unknown autoscaling setting cpux"},
+		{spec: "min=2,max=10,memroy=80", err: "unknown autoscaling setting memroy"},
+		{spec: "min=2,max=10,metric:=100", err: "unknown autoscaling setting metric:"},
+		{spec: "min=2,max", err: "must be of the form key=value"},
+		{spec: "min=,max=3", err: "must be of the form key=value"},
+		{spec: "", err: "must be of the form key=value"},
+		{spec: "min=two,max=3", err: "invalid autoscaling min"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			autoscale, err := ParseAutoscale(test.spec)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("ParseAutoscale(%q) error = %v, want one containing %q", test.spec, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAutoscale(%q) returned error: %v", test.spec, err)
+			}
+			if !reflect.DeepEqual(autoscale, test.want) {
+				t.Errorf("ParseAutoscale(%q) = %+v, want %+v", test.spec, autoscale, test.want)
+			}
+		})
+	}
+}
+
+func TestAutoscaleValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		autoscale *Autoscale
+		err       string
+	}{
+		{name: "disabled", autoscale: nil},
+		{name: "cpu", autoscale: &Autoscale{Min: 1, Max: 1, CPU: 70}},
+		{name: "custom metric", autoscale: &Autoscale{Min: 1, Max: 5, Metrics: []Metric{{Name: "rps", Target: "100"}}}},
+		{name: "min below one", autoscale: &Autoscale{Min: 0, Max: 5, CPU: 70}, err: "min must be at least 1"},
+		{name: "max below min", autoscale: &Autoscale{Min: 5, Max: 2, CPU: 70}, err: "max 2 must not be less than min 5"},
+		{name: "negative utilization", autoscale: &Autoscale{Min: 1, Max: 2, CPU: -1}, err: "must not be negative"},
+		{name: "no target", autoscale: &Autoscale{Min: 1, Max: 2}, err: "needs a cpu, memory or custom metric target"},
+		{name: "invalid metric target", autoscale: &Autoscale{Min: 1, Max: 2, Metrics: []Metric{{Name: "rps", Target: "lots"}}}, err: "invalid target"},
+		{name: "zero metric target", autoscale: &Autoscale{Min: 1, Max: 2, Metrics: []Metric{{Name: "rps", Target: "0"}}}, err: "must be greater than zero"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.autoscale.Validate()
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("Validate returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Validate error = %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
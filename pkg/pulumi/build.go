@@ -0,0 +1,149 @@
+package pulumi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi-docker/sdk/v3/go/docker"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Builders ploy can build the app's image with
+// Without a Dockerfile, ploy generates one for Go, Node and Python projects or uses Cloud Native Buildpacks
+const (
+	BuilderDockerfile = "dockerfile"
+	BuilderBuildpacks = "buildpacks"
+	BuilderGo         = "go"
+	BuilderNode       = "node"
+	BuilderPython     = "python"
+)
+
+// Build configures how the app's image is built
+// Builder is detected from the build context unless it's pinned
+// Dockerfile is relative to the working directory and defaults to the one in the build context
+type Build struct {
+	Builder           string
+	BuildpacksBuilder string
+
+	Dockerfile string
+	Target     string
+	Args       map[string]string
+	// SecretArgs are build args read from the environment when building, their values are never stored
+	SecretArgs []string
+	CacheFrom  []string
+	Platform   string
+
+	// GeneratedDockerfile is the Dockerfile ploy wrote for builders other than dockerfile
+	GeneratedDockerfile string `json:"-"`
+}
+
+// ParseBuildArgs parses build args given as KEY=value, a KEY on its own is a secret read from the environment
+func ParseBuildArgs(values []string) (map[string]string, []string, error) {
+	args := make(map[string]string)
+	var secrets []string
+
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if err := ValidateEnvName(parts[0]); err != nil {
+			return nil, nil, fmt.Errorf("invalid build arg %q: %v", value, err)
+		}
+		if len(parts) == 1 {
+			secrets = append(secrets, parts[0])
+			continue
+		}
+		args[parts[0]] = parts[1]
+	}
+
+	return args, secrets, nil
+}
+
+// Validate checks the build settings, and that secret build args are set in the environment
+func (b Build) Validate() error {
+	switch b.Builder {
+	case "", BuilderDockerfile, BuilderGo, BuilderNode, BuilderPython:
+	case BuilderBuildpacks:
+		if b.Target != "" || len(b.CacheFrom) > 0 || b.Platform != "" {
+			return fmt.Errorf("target, cache-from and platform can't be used with the buildpacks builder")
+		}
+	default:
+		return fmt.Errorf("unknown builder %q, must be one of dockerfile, buildpacks, go, node or python", b.Builder)
+	}
+
+	if b.BuildpacksBuilder != "" && b.Builder != BuilderBuildpacks {
+		return fmt.Errorf("a buildpacks builder image needs the builder to be buildpacks")
+	}
+
+	for _, name := range b.SecretArgs {
+		if _, ok := b.Args[name]; ok {
+			return fmt.Errorf("build arg %s is given both a value and as a secret", name)
+		}
+		if _, ok := os.LookupEnv(name); !ok {
+			return fmt.Errorf("secret build arg %s must be set in the environment", name)
+		}
+	}
+
+	for _, ref := range b.CacheFrom {
+		if err := ValidateImage(ref); err != nil {
+			return fmt.Errorf("invalid cache-from image: %v", err)
+		}
+	}
+
+	if b.Platform != "" {
+		parts := strings.Split(b.Platform, "/")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid platform %q, must be of the form os/arch[/variant], e.g. linux/arm64", b.Platform)
+		}
+	}
+
+	return nil
+}
+
+// Dockerfile returns the path of the Dockerfile the app is built from
+func (args *PloyDeploymentArgs) Dockerfile() string {
+	if args.Build.Dockerfile != "" {
+		return args.Build.Dockerfile
+	}
+	return filepath.Join(args.Directory, "Dockerfile")
+}
+
+// dockerBuild converts the build settings into the arguments for docker build
+func (args *PloyDeploymentArgs) dockerBuild() docker.DockerBuildArgs {
+	build := args.Build
+
+	buildArgs := pulumi.StringMap{}
+	for key, value := range build.Args {
+		buildArgs[key] = pulumi.String(value)
+	}
+
+	// docker reads build args given without a value from its environment, which it inherits from ploy,
+	// so secret values never pass through Pulumi or show up in its logs
+	var options []string
+	for _, name := range build.SecretArgs {
+		options = append(options, "--build-arg", name)
+	}
+	for _, ref := range build.CacheFrom {
+		options = append(options, "--cache-from", ref)
+	}
+	if build.Platform != "" {
+		options = append(options, "--platform", build.Platform)
+	}
+
+	dockerfile := build.Dockerfile
+	if build.GeneratedDockerfile != "" {
+		dockerfile = build.GeneratedDockerfile
+	}
+
+	dockerBuild := docker.DockerBuildArgs{
+		Context:      pulumi.String(args.Directory),
+		Dockerfile:   pulumi.String(dockerfile),
+		Args:         buildArgs,
+		ExtraOptions: pulumi.ToStringArray(options),
+	}
+	if build.Target != "" {
+		dockerBuild.Target = pulumi.String(build.Target)
+	}
+
+	return dockerBuild
+}
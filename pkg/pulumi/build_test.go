@@ -0,0 +1,93 @@
+package pulumi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBuildArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		args    map[string]string
+		secrets []string
+		err     string
+	}{
+		{name: "none", values: nil, args: map[string]string{}},
+		{name: "values", values: []string{"VERSION=1.2.3", "GOFLAGS=-mod=vendor"}, args: map[string]string{"VERSION": "1.2.3", "GOFLAGS": "-mod=vendor"}},
+		{name: "empty value", values: []string{"DEBUG="}, args: map[string]string{"DEBUG": ""}},
+		{name: "bare secret", values: []string{"NPM_TOKEN"}, args: map[string]string{}, secrets: []string{"NPM_TOKEN"}},
+		{
+			name:    "values and secrets",
+			values:  []string{"VERSION=1", "NPM_TOKEN", "GITHUB_TOKEN"},
+			args:    map[string]string{"VERSION": "1"},
+			secrets: []string{"NPM_TOKEN", "GITHUB_TOKEN"},
+		},
+		{name: "invalid name", values: []string{"1VERSION=1"}, err: "invalid build arg \"1VERSION=1\""},
+		{name: "invalid secret name", values: []string{"NPM-TOKEN"}, err: "invalid build arg \"NPM-TOKEN\""},
+		{name: "empty name", values: []string{"=1"}, err: "invalid build arg"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, secrets, err := ParseBuildArgs(test.values)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("ParseBuildArgs(%q) error = %v, want one containing %q", test.values, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBuildArgs(%q) returned error: %v", test.values, err)
+			}
+			if !reflect.DeepEqual(args, test.args) {
+				t.Errorf("ParseBuildArgs(%q) args = %v, want %v", test.values, args, test.args)
+			}
+			if !reflect.DeepEqual(secrets, test.secrets) {
+				t.Errorf("ParseBuildArgs(%q) secrets = %q, want %q", test.values, secrets, test.secrets)
+			}
+		})
+	}
+}
+
+func TestBuildValidate(t *testing.T) {
+	t.Setenv("PLOY_TEST_TOKEN", "secret")
+
+	tests := []struct {
+		name  string
+		build Build
+		err   string
+	}{
+		{name: "defaults", build: Build{}},
+		{name: "secret in the environment", build: Build{SecretArgs: []string{"PLOY_TEST_TOKEN"}}},
+		{name: "buildpacks", build: Build{Builder: BuilderBuildpacks, BuildpacksBuilder: "paketobuildpacks/builder:base"}},
+		{name: "platform", build: Build{Platform: "linux/arm64/v8"}},
+		{name: "secret not in the environment", build: Build{SecretArgs: []string{"PLOY_TEST_MISSING"}}, err: "secret build arg PLOY_TEST_MISSING must be set in the environment"},
+		{
+			name:  "secret with a value",
+			build: Build{Args: map[string]string{"PLOY_TEST_TOKEN": "x"}, SecretArgs: []string{"PLOY_TEST_TOKEN"}},
+			err:   "build arg PLOY_TEST_TOKEN is given both a value and as a secret",
+		},
+		{name: "unknown builder", build: Build{Builder: "make"}, err: "unknown builder \"make\""},
+		{name: "buildpacks with a target", build: Build{Builder: BuilderBuildpacks, Target: "prod"}, err: "can't be used with the buildpacks builder"},
+		{name: "buildpacks builder image without buildpacks", build: Build{BuildpacksBuilder: "paketobuildpacks/builder:base"}, err: "needs the builder to be buildpacks"},
+		{name: "invalid cache-from", build: Build{CacheFrom: []string{"Acme/App"}}, err: "invalid cache-from image"},
+		{name: "invalid platform", build: Build{Platform: "arm64"}, err: "invalid platform \"arm64\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.build.Validate()
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("Validate returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Validate error = %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package pulumi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var domainName = regexp.MustCompile(`^(\*\.)?([a-z0-9]([-a-z0-9]*[a-z0-9])?\.)+[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// TLS configures certificates for the app's domains
+// Issuer is a cert-manager ClusterIssuer, which needs ingress mode as cloud load balancers can't use its certificates
+// CertificateArn is an ACM certificate, used by AWS load balancers and the ALB ingress class
+type TLS struct {
+	Issuer         string
+	CertificateArn string
+}
+
+// ValidateDomain checks a domain is a lowercase hostname, optionally a wildcard
+func ValidateDomain(domain string) error {
+	if len(domain) > 253 || !domainName.MatchString(domain) {
+		return fmt.Errorf("invalid domain %q: must be a lowercase hostname such as api.example.com", domain)
+	}
+	return nil
+}
+
+// validateTLS checks the certificate source can be used with the way the app is exposed
+func (args *PloyDeploymentArgs) validateTLS() error {
+	tls := args.TLS
+	if tls == nil {
+		return nil
+	}
+
+	if tls.Issuer == "" && tls.CertificateArn == "" {
+		return fmt.Errorf("tls needs either a cert-manager issuer or an ACM certificate ARN")
+	}
+	if tls.Issuer != "" && tls.CertificateArn != "" {
+		return fmt.Errorf("tls can use a cert-manager issuer or an ACM certificate ARN, not both")
+	}
+
+	if tls.Issuer != "" {
+		if args.Ingress == nil {
+			return fmt.Errorf("cert-manager certificates need ingress mode, use an ACM certificate ARN for load balancers")
+		}
+		if len(args.hosts()) == 0 {
+			return fmt.Errorf("cert-manager certificates need an ingress host or at least one domain")
+		}
+	}
+
+	if tls.CertificateArn != "" {
+		if !strings.HasPrefix(tls.CertificateArn, "arn:") {
+			return fmt.Errorf("invalid ACM certificate ARN %s", tls.CertificateArn)
+		}
+		if args.Ingress != nil && args.Ingress.Class != IngressClassALB {
+			return fmt.Errorf("ACM certificates can only be used by load balancers or the alb ingress class")
+		}
+
+		// the load balancer listens for https on 443 alongside the app's own service ports
+		if args.Ingress == nil {
+			ports := args.ports()
+			https := Port{Name: "https", Port: ports[0].Port, ServicePort: 443, Protocol: "TCP"}
+			if err := ValidatePorts(append(append([]Port{}, ports...), https)); err != nil {
+				return fmt.Errorf("ACM certificates add an https port on 443 to the load balancer: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hosts returns every hostname the app is served on, the ingress host followed by its domains
+func (args *PloyDeploymentArgs) hosts() []string {
+	var hosts []string
+	if args.Ingress != nil && args.Ingress.Host != "" {
+		hosts = append(hosts, args.Ingress.Host)
+	}
+	for _, domain := range args.Domains {
+		if len(hosts) == 0 || domain != hosts[0] {
+			hosts = append(hosts, domain)
+		}
+	}
+	return hosts
+}
+
+// scheme returns the URL scheme the app is served with
+func (args *PloyDeploymentArgs) scheme() string {
+	if args.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
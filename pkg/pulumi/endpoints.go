@@ -0,0 +1,153 @@
+package pulumi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultAddressTimeout is how long to wait for a load balancer to get an address, when it isn't set
+const DefaultAddressTimeout = 5 * time.Minute
+
+// addressTimeout returns how long to wait for the app's load balancer to get an address
+func (args *PloyDeploymentArgs) addressTimeout() time.Duration {
+	if args.AddressTimeout <= 0 {
+		return DefaultAddressTimeout
+	}
+	return args.AddressTimeout
+}
+
+// loadBalancerHost returns the hostname or IP of a load balancer, or an empty string if it doesn't have one yet
+func loadBalancerHost(status *corev1.LoadBalancerStatus) string {
+	if status == nil {
+		return ""
+	}
+	for _, ingress := range status.Ingress {
+		if ingress.Hostname != nil && *ingress.Hostname != "" {
+			return *ingress.Hostname
+		}
+		if ingress.Ip != nil && *ingress.Ip != "" {
+			return *ingress.Ip
+		}
+	}
+	return ""
+}
+
+// resolveHost returns the load balancer host from a status, waiting for it with wait when there isn't one yet
+// Previews don't wait, nor does a nil wait, and a load balancer that never gets an address leaves the app without
+// one rather than failing
+func (args *PloyDeploymentArgs) resolveHost(ctx *pulumi.Context, name string, status *corev1.LoadBalancerStatus, wait func(ctx context.Context, client *kube.Client) (string, error)) string {
+	if host := loadBalancerHost(status); host != "" || ctx.DryRun() {
+		return host
+	}
+	if wait == nil {
+		log.Infof("The Service of %s doesn't report a load balancer address, so it has no address to export", name)
+		return ""
+	}
+
+	client, err := kube.NewClient()
+	if err != nil {
+		log.Warnf("Unable to wait for the address of %s: %v", name, err)
+		return ""
+	}
+
+	log.Infof("Waiting up to %s for the load balancer of %s to get an address", args.addressTimeout(), name)
+	waitCtx, cancel := context.WithTimeout(context.Background(), args.addressTimeout())
+	defer cancel()
+
+	host, err := wait(waitCtx, client)
+	if err != nil {
+		log.Warnf("%v, run ploy get once it's ready", err)
+		return ""
+	}
+	return host
+}
+
+// endpoints lists the ways the app can be reached on a host, one for each port published by its Service, or
+// the single route through the ingress controller
+func (args *PloyDeploymentArgs) endpoints(host string) []interface{} {
+	if host == "" {
+		return nil
+	}
+
+	endpoint := func(name string, scheme string, port int) map[string]interface{} {
+		return map[string]interface{}{"name": name, "scheme": scheme, "host": host, "port": port}
+	}
+
+	if args.Ingress != nil {
+		port := 80
+		if args.scheme() == "https" {
+			port = 443
+		}
+		return []interface{}{endpoint(args.PrimaryPort().Name, args.scheme(), port)}
+	}
+
+	var endpoints []interface{}
+	for _, port := range args.ports() {
+		endpoints = append(endpoints, endpoint(port.Name, port.scheme(), port.ServicePort))
+	}
+	if args.exposure() != ExposeInternal && args.TLS != nil && args.TLS.CertificateArn != "" {
+		endpoints = append(endpoints, endpoint("https", "https", 443))
+	}
+	return endpoints
+}
+
+// addressPort returns the port clients use with the app's address and scheme
+// The ingress controller and an ACM certificate's https listener use the scheme's default port, otherwise
+// it's the Service port of the primary port
+func (args *PloyDeploymentArgs) addressPort() int {
+	switch {
+	case args.Ingress != nil && args.scheme() == "https":
+		return 443
+	case args.Ingress != nil:
+		return 80
+	case args.exposure() != ExposeInternal && args.TLS != nil && args.TLS.CertificateArn != "":
+		return 443
+	}
+	return args.PrimaryPort().ServicePort
+}
+
+// exportEndpoints exports the app's address, as a single host for ploy get, and every endpoint it can be reached on
+// The address includes its port whenever that isn't the default for the app's scheme
+func (args *PloyDeploymentArgs) exportEndpoints(ctx *pulumi.Context, name string, host pulumi.StringOutput) {
+	ctx.Export("address", host.ApplyT(func(host string) *string {
+		if host == "" {
+			return nil
+		}
+
+		address := host
+		port := args.addressPort()
+		if (args.scheme() == "http" && port != 80) || (args.scheme() == "https" && port != 443) {
+			address = fmt.Sprintf("%s:%d", host, port)
+		}
+
+		if args.exposure() == ExposeInternal {
+			log.Infof("Your service is available inside the cluster at: %v", address)
+		} else {
+			log.Infof("Your service is available at: %v", address)
+		}
+		return &address
+	}))
+
+	ctx.Export("endpoints", host.ApplyT(func(host string) []interface{} {
+		return args.endpoints(host)
+	}))
+}
+
+// scheme returns the scheme clients use for a port, ports without an application protocol are assumed to be HTTP
+// like the default readiness probe does
+func (p Port) scheme() string {
+	switch {
+	case p.AppProtocol != "":
+		return strings.ToLower(p.AppProtocol)
+	case p.Protocol == "TCP":
+		return "http"
+	}
+	return strings.ToLower(p.Protocol)
+}
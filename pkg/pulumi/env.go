@@ -0,0 +1,33 @@
+package pulumi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvName checks an environment variable name can be passed to the container
+func ValidateEnvName(name string) error {
+	if !envName.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q: must contain only letters, digits and underscores, and not start with a digit", name)
+	}
+	return nil
+}
+
+// ParseEnv parses a list of NAME=value assignments
+func ParseEnv(assignments []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, assignment := range assignments {
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid assignment %q: must be of the form NAME=value", assignment)
+		}
+		if err := ValidateEnvName(parts[0]); err != nil {
+			return nil, err
+		}
+		env[parts[0]] = parts[1]
+	}
+	return env, nil
+}
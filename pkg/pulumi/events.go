@@ -29,8 +29,21 @@ func CollectEvents(eventChannel <-chan events.EngineEvent) {
 				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Namespace")
 			case "kubernetes:core/v1:Service":
 				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Service")
+			case "kubernetes:core/v1:ConfigMap":
+				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes ConfigMap")
+			case "kubernetes:core/v1:Secret":
+				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Secret")
 			case "kubernetes:apps/v1:Deployment":
 				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Deployment")
+			case "kubernetes:networking.k8s.io/v1:Ingress":
+				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes Ingress")
+			case "kubernetes:autoscaling/v2:HorizontalPodAutoscaler":
+				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Kubernetes HorizontalPodAutoscaler")
+			case "kubernetes:batch/v1:Job":
+				// the release command only runs again when the job is replaced
+				if event.ResourcePreEvent.Metadata.Op != "same" {
+					createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Running release command")
+				}
 			case "docker:image:Image":
 				createLogger.WithFields(log.Fields{"resource": event.ResourcePreEvent.Metadata.Type}).Info("Creating Docker Image")
 			}
@@ -44,8 +57,20 @@ func CollectEvents(eventChannel <-chan events.EngineEvent) {
 				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Namespace")
 			case "kubernetes:core/v1:Service":
 				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Service")
+			case "kubernetes:core/v1:ConfigMap":
+				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes ConfigMap")
+			case "kubernetes:core/v1:Secret":
+				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Secret")
 			case "kubernetes:apps/v1:Deployment":
 				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Deployment")
+			case "kubernetes:networking.k8s.io/v1:Ingress":
+				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes Ingress")
+			case "kubernetes:autoscaling/v2:HorizontalPodAutoscaler":
+				completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Kubernetes HorizontalPodAutoscaler")
+			case "kubernetes:batch/v1:Job":
+				if event.ResOutputsEvent.Metadata.Op != "same" {
+					completeLogger.WithFields(log.Fields{"resource": event.ResOutputsEvent.Metadata.Type}).Info("Release command succeeded")
+				}
 			case "docker:image:Image":
 				completeLogger.WithFields(log.Fields{"name": event.ResOutputsEvent.Metadata.New.Outputs["baseImageName"], "resource": event.ResOutputsEvent.Metadata.Type}).Info("Created Docker Image")
 			}
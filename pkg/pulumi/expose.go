@@ -0,0 +1,111 @@
+package pulumi
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Ways an app can be exposed
+// Internal apps are only reachable inside the cluster, private ones through a load balancer inside the VPC,
+// and public ones from the internet, optionally only from SourceRanges
+const (
+	ExposeInternal = "internal"
+	ExposePrivate  = "private"
+	ExposePublic   = "public"
+)
+
+// exposure returns how the app is exposed, apps are public unless told otherwise
+func (args *PloyDeploymentArgs) exposure() string {
+	if args.Expose == "" {
+		return ExposePublic
+	}
+	return args.Expose
+}
+
+// ValidateExposure checks an exposure mode and its source ranges make sense together and with the app's ingress
+func ValidateExposure(expose string, sourceRanges []string, ingress *Ingress) error {
+	if expose == "" {
+		expose = ExposePublic
+	}
+	switch expose {
+	case ExposeInternal, ExposePrivate, ExposePublic:
+	default:
+		return fmt.Errorf("unknown expose mode %s, must be internal, private or public", expose)
+	}
+
+	for _, cidr := range sourceRanges {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid source range %s, must be a CIDR like 203.0.113.0/24", cidr)
+		}
+	}
+	if len(sourceRanges) > 0 && expose != ExposePublic {
+		return fmt.Errorf("source ranges only apply to public apps, %s apps can't be reached from the internet", expose)
+	}
+
+	if ingress != nil {
+		if expose == ExposeInternal {
+			return fmt.Errorf("internal apps can't be routed through an ingress, remove the ingress settings")
+		}
+		if expose == ExposePrivate && ingress.Class != IngressClassALB {
+			return fmt.Errorf("private apps can only be routed through the alb ingress class, other controllers run their own load balancer")
+		}
+		if len(sourceRanges) > 0 && ingress.Class != IngressClassALB && ingress.Class != IngressClassNginx {
+			return fmt.Errorf("source ranges through an ingress need the alb or nginx ingress class")
+		}
+	}
+
+	return nil
+}
+
+// validateExposure also checks internal apps have no domains, which are managed separately
+func (args *PloyDeploymentArgs) validateExposure() error {
+	if err := ValidateExposure(args.Expose, args.SourceRanges, args.Ingress); err != nil {
+		return err
+	}
+	if args.exposure() == ExposeInternal && (len(args.Domains) > 0 || args.TLS != nil) {
+		return fmt.Errorf("internal apps have no load balancer for domains to point at, remove them with ploy domains")
+	}
+	return nil
+}
+
+// service returns the type and annotations of the app's Service for how it's exposed
+func (args *PloyDeploymentArgs) service() (pulumi.String, pulumi.StringMap) {
+	switch {
+	case args.Ingress != nil:
+		// the ingress controller's load balancer is shared, so the app only needs an internal Service
+		return "ClusterIP", pulumi.StringMap{}
+	case args.exposure() == ExposeInternal:
+		return "ClusterIP", pulumi.StringMap{}
+	}
+
+	private := args.exposure() == ExposePrivate
+	if args.Nlb {
+		annotations := pulumi.StringMap{
+			"service.beta.kubernetes.io/aws-load-balancer-type": pulumi.String("nlb-ip"),
+		}
+		if private {
+			annotations["service.beta.kubernetes.io/aws-load-balancer-scheme"] = pulumi.String("internal")
+			annotations["service.beta.kubernetes.io/aws-load-balancer-internal"] = pulumi.String("true")
+		}
+		return "NodePort", annotations
+	}
+
+	annotations := pulumi.StringMap{}
+	if private {
+		annotations["service.beta.kubernetes.io/aws-load-balancer-internal"] = pulumi.String("true")
+	}
+	return "LoadBalancer", annotations
+}
+
+// internalHost is the DNS name of the app's Service inside the cluster
+func internalHost(name string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", name, name)
+}
+
+// sourceRanges returns the allowed source ranges as a single comma separated value, as ingress annotations take them
+func (args *PloyDeploymentArgs) sourceRanges() string {
+	return strings.Join(args.SourceRanges, ",")
+}
@@ -0,0 +1,80 @@
+package pulumi
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaxxstorm/ploy/pkg/kube"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/batch/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	log "github.com/sirupsen/logrus"
+)
+
+// newReleaseJob runs the app's release command with the new image, before the Deployment is updated
+// The Job is auto-named, so a new image or environment replaces it and runs the command again, and Pulumi waits
+// for it to complete, failing the update if it doesn't
+func newReleaseJob(ctx *pulumi.Context, name string, args *PloyDeploymentArgs, image pulumi.StringOutput, envFrom corev1.EnvFromSourceArray, imagePullSecrets corev1.LocalObjectReferenceArray, namespace pulumi.StringInput, labels pulumi.StringMap, opts ...pulumi.ResourceOption) (*batchv1.Job, error) {
+	if len(args.ReleaseCommand) == 0 {
+		return nil, nil
+	}
+
+	return batchv1.NewJob(ctx, name+"-release", &batchv1.JobArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Namespace: namespace,
+			Labels:    labels,
+			Annotations: pulumi.StringMap{
+				// a release that failed is run again by the next update, rather than waited on
+				"pulumi.com/replaceUnready": pulumi.String("true"),
+			},
+		},
+		Spec: batchv1.JobSpecArgs{
+			BackoffLimit: pulumi.Int(0),
+			Template: corev1.PodTemplateSpecArgs{
+				Metadata: &metav1.ObjectMetaArgs{
+					// the app's labels are left off, so its Service doesn't send traffic to the job
+					Labels: pulumi.StringMap{
+						kube.JobLabel: pulumi.String(kube.JobRelease),
+					},
+				},
+				Spec: corev1.PodSpecArgs{
+					RestartPolicy:    pulumi.String("Never"),
+					ImagePullSecrets: imagePullSecrets,
+					Containers: corev1.ContainerArray{
+						corev1.ContainerArgs{
+							Name:      pulumi.String(kube.ContainerName),
+							Image:     image,
+							Command:   pulumi.ToStringArray(args.ReleaseCommand),
+							EnvFrom:   envFrom,
+							Resources: args.Resources.resourceRequirements(),
+						},
+					},
+				},
+			},
+		},
+	}, opts...)
+}
+
+// followRelease logs the output of release jobs started during an update, returning a function that stops it
+func followRelease(name string) func() {
+	kubeClient, err := kube.NewClient()
+	if err != nil {
+		log.Debugf("Unable to follow release command logs: %v", err)
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		kubeClient.FollowJobs(ctx, name, kube.JobRelease, time.Now(), func(pod string, line string) {
+			log.WithFields(log.Fields{"event": "RELEASE", "pod": pod}).Info(line)
+		})
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,20 @@
+package pulumi
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// imageReference loosely matches a docker image reference, such as registry.example.com:5000/team/app:v1 or app@sha256:...
+var imageReference = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)*(:[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(@sha256:[a-f0-9]{64})?$`)
+
+// ValidateImage checks an image reference can be pulled by the cluster
+func ValidateImage(image string) error {
+	if image == "" {
+		return fmt.Errorf("no image given")
+	}
+	if !imageReference.MatchString(image) {
+		return fmt.Errorf("invalid image reference %q, must be of the form registry/repository[:tag][@sha256:digest]", image)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package pulumi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateImage(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		image string
+		err   string
+	}{
+		{image: "nginx"},
+		{image: "nginx:1.21"},
+		{image: "ghcr.io/acme/app:v1.2.3"},
+		{image: "registry.example.com:5000/team/app:latest"},
+		{image: "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:abc123"},
+		{image: "app@" + digest},
+		{image: "ghcr.io/acme/app:v1@" + digest},
+		{image: "", err: "no image given"},
+		{image: "ghcr.io/Acme/app", err: "invalid image reference"},
+		{image: "app:", err: "invalid image reference"},
+		{image: "app:-tag", err: "invalid image reference"},
+		{image: "app@sha256:abc", err: "invalid image reference"},
+		{image: "app:" + strings.Repeat("v", 129), err: "invalid image reference"},
+		{image: "https://ghcr.io/acme/app", err: "invalid image reference"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			err := ValidateImage(test.image)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("ValidateImage(%q) returned error: %v", test.image, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("ValidateImage(%q) error = %v, want one containing %q", test.image, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package pulumi
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	networkingv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Ingress classes with settings ploy knows how to fill in
+const (
+	IngressClassNginx = "nginx"
+	IngressClassALB   = "alb"
+)
+
+// Ingress routes traffic to the app through a shared ingress controller instead of its own load balancer
+// Host is optional, without it the app receives traffic for every host sent to the controller
+type Ingress struct {
+	Host  string
+	Path  string
+	Class string
+}
+
+// Validate checks the path is absolute and the host is a plain hostname
+func (i *Ingress) Validate() error {
+	if i == nil {
+		return nil
+	}
+
+	if i.Path != "" && !strings.HasPrefix(i.Path, "/") {
+		return fmt.Errorf("ingress path %s must start with /", i.Path)
+	}
+
+	if strings.Contains(i.Host, "://") || strings.ContainsAny(i.Host, "/:") {
+		return fmt.Errorf("ingress host %s must be a hostname, without a scheme, port or path", i.Host)
+	}
+
+	return nil
+}
+
+// annotations returns the controller specific annotations for the ingress class and how the app is exposed
+func (i *Ingress) annotations(args *PloyDeploymentArgs) pulumi.StringMap {
+	annotations := pulumi.StringMap{}
+
+	switch i.Class {
+	case IngressClassALB:
+		// The ALB controller has to target pods directly, as the Service is only a ClusterIP
+		scheme := "internet-facing"
+		if args.exposure() == ExposePrivate {
+			scheme = "internal"
+		}
+		annotations["alb.ingress.kubernetes.io/scheme"] = pulumi.String(scheme)
+		annotations["alb.ingress.kubernetes.io/target-type"] = pulumi.String("ip")
+		if tls := args.TLS; tls != nil && tls.CertificateArn != "" {
+			annotations["alb.ingress.kubernetes.io/certificate-arn"] = pulumi.String(tls.CertificateArn)
+			annotations["alb.ingress.kubernetes.io/listen-ports"] = pulumi.String(`[{"HTTP": 80}, {"HTTPS": 443}]`)
+		}
+		if len(args.SourceRanges) > 0 {
+			annotations["alb.ingress.kubernetes.io/inbound-cidrs"] = pulumi.String(args.sourceRanges())
+		}
+	case IngressClassNginx:
+		if len(args.SourceRanges) > 0 {
+			annotations["nginx.ingress.kubernetes.io/whitelist-source-range"] = pulumi.String(args.sourceRanges())
+		}
+	}
+
+	// cert-manager requests the certificate for the ingress's TLS hosts from the issuer
+	if tls := args.TLS; tls != nil && tls.Issuer != "" {
+		annotations["cert-manager.io/cluster-issuer"] = pulumi.String(tls.Issuer)
+	}
+
+	return annotations
+}
+
+// newIngress routes the app's hosts and ingress path to the primary port of its Service
+// Without any hosts, a single rule routes every host sent to the controller
+func newIngress(ctx *pulumi.Context, name string, args *PloyDeploymentArgs, service *corev1.Service, primary Port, labels pulumi.StringMap, opts ...pulumi.ResourceOption) (*networkingv1.Ingress, error) {
+	path := args.Ingress.Path
+	if path == "" {
+		path = "/"
+	}
+
+	http := &networkingv1.HTTPIngressRuleValueArgs{
+		Paths: networkingv1.HTTPIngressPathArray{
+			networkingv1.HTTPIngressPathArgs{
+				Path:     pulumi.String(path),
+				PathType: pulumi.String("Prefix"),
+				Backend: networkingv1.IngressBackendArgs{
+					Service: &networkingv1.IngressServiceBackendArgs{
+						Name: service.Metadata.Name().Elem(),
+						Port: &networkingv1.ServiceBackendPortArgs{
+							Number: pulumi.Int(primary.ServicePort),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	hosts := args.hosts()
+	rules := networkingv1.IngressRuleArray{}
+	for _, host := range hosts {
+		rules = append(rules, networkingv1.IngressRuleArgs{
+			Host: pulumi.String(host),
+			Http: http,
+		})
+	}
+	if len(hosts) == 0 {
+		rules = append(rules, networkingv1.IngressRuleArgs{Http: http})
+	}
+
+	spec := &networkingv1.IngressSpecArgs{
+		Rules: rules,
+	}
+	if args.Ingress.Class != "" {
+		spec.IngressClassName = pulumi.String(args.Ingress.Class)
+	}
+
+	// cert-manager stores the certificate in the TLS secret, which the ingress controller serves for the hosts
+	if args.TLS != nil && args.TLS.Issuer != "" {
+		spec.Tls = networkingv1.IngressTLSArray{
+			networkingv1.IngressTLSArgs{
+				Hosts:      pulumi.ToStringArray(hosts),
+				SecretName: pulumi.String(fmt.Sprintf("%s-tls", name)),
+			},
+		}
+	}
+
+	return networkingv1.NewIngress(ctx, name, &networkingv1.IngressArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:        pulumi.String(name),
+			Namespace:   service.Metadata.Namespace().Elem(),
+			Labels:      labels,
+			Annotations: args.Ingress.annotations(args),
+		},
+		Spec: spec,
+	}, opts...)
+}
@@ -18,7 +18,7 @@ func EnsurePlugins(workspace auto.Workspace) error {
 	if err != nil {
 		return fmt.Errorf("error installing aws plugin: %v", err)
 	}
-	err = workspace.InstallPlugin(ctx, "kubernetes", "v2.6.3")
+	err = workspace.InstallPlugin(ctx, "kubernetes", "v3.20.0")
 	if err != nil {
 		return fmt.Errorf("error installing kubernetes plugin: %v", err)
 	}
@@ -0,0 +1,132 @@
+package pulumi
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var portName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Port is a port exposed by the application container and published by its Service
+type Port struct {
+	Name        string
+	Port        int
+	ServicePort int
+	Protocol    string
+	AppProtocol string
+}
+
+// DefaultPorts is used when no ports are specified, matching the nginx example app
+func DefaultPorts() []Port {
+	return []Port{{Name: "http", Port: 80, ServicePort: 80, Protocol: "TCP", AppProtocol: "http"}}
+}
+
+// appProtocols are the application protocol hints a port can be given in place of tcp, udp or sctp
+var appProtocols = map[string]bool{"http": true, "https": true, "grpc": true, "h2c": true, "ws": true, "wss": true}
+
+// ParsePort parses a port specification of the form [name=][servicePort:]port[/protocol]
+// protocol may be tcp, udp or sctp, or an application protocol hint such as http or grpc, which implies tcp
+func ParsePort(spec string) (Port, error) {
+	var port Port
+	rest := strings.TrimSpace(spec)
+
+	if i := strings.Index(rest, "="); i >= 0 {
+		port.Name = rest[:i]
+		rest = rest[i+1:]
+	}
+
+	protocol := "tcp"
+	if i := strings.Index(rest, "/"); i >= 0 {
+		protocol = strings.ToLower(rest[i+1:])
+		rest = rest[:i]
+	}
+
+	switch protocol {
+	case "tcp", "udp", "sctp":
+		port.Protocol = strings.ToUpper(protocol)
+	case "":
+		return port, fmt.Errorf("invalid port %q: empty protocol", spec)
+	default:
+		if !appProtocols[protocol] {
+			return port, fmt.Errorf("invalid port %q: unknown protocol %s, must be tcp, udp, sctp, http, https, grpc, h2c, ws or wss", spec, protocol)
+		}
+		port.Protocol = "TCP"
+		port.AppProtocol = protocol
+	}
+
+	numbers := strings.SplitN(rest, ":", 2)
+	containerPort, err := strconv.Atoi(numbers[len(numbers)-1])
+	if err != nil {
+		return port, fmt.Errorf("invalid port %q: %v", spec, err)
+	}
+	port.Port = containerPort
+	port.ServicePort = containerPort
+
+	if len(numbers) == 2 {
+		servicePort, err := strconv.Atoi(numbers[0])
+		if err != nil {
+			return port, fmt.Errorf("invalid service port %q: %v", spec, err)
+		}
+		port.ServicePort = servicePort
+	}
+
+	if port.Name == "" {
+		if port.AppProtocol != "" {
+			port.Name = fmt.Sprintf("%s-%d", port.AppProtocol, port.Port)
+		} else {
+			port.Name = fmt.Sprintf("%s-%d", strings.ToLower(port.Protocol), port.Port)
+		}
+	}
+
+	return port, nil
+}
+
+// ParsePorts parses a list of port specifications and checks they can be used together
+func ParsePorts(specs []string) ([]Port, error) {
+	var ports []Port
+	for _, spec := range specs {
+		port, err := ParsePort(spec)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+	}
+
+	if err := ValidatePorts(ports); err != nil {
+		return nil, err
+	}
+
+	return ports, nil
+}
+
+// ValidatePorts checks the port numbers are in range and names and service ports don't collide
+func ValidatePorts(ports []Port) error {
+	names := make(map[string]bool)
+	servicePorts := make(map[string]bool)
+
+	for _, port := range ports {
+		if port.Port < 1 || port.Port > 65535 {
+			return fmt.Errorf("port %d for %s is out of range", port.Port, port.Name)
+		}
+		if port.ServicePort < 1 || port.ServicePort > 65535 {
+			return fmt.Errorf("service port %d for %s is out of range", port.ServicePort, port.Name)
+		}
+		if len(port.Name) > 15 || !portName.MatchString(port.Name) {
+			return fmt.Errorf("port name %s must be no more than 15 lowercase alphanumeric characters or '-'", port.Name)
+		}
+		if names[port.Name] {
+			return fmt.Errorf("port name %s is used more than once", port.Name)
+		}
+		names[port.Name] = true
+
+		key := fmt.Sprintf("%d/%s", port.ServicePort, port.Protocol)
+		if servicePorts[key] {
+			return fmt.Errorf("service port %s is used more than once", key)
+		}
+		servicePorts[key] = true
+	}
+
+	return nil
+}
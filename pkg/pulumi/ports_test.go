@@ -0,0 +1,121 @@
+package pulumi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Port
+		err  string
+	}{
+		{spec: "8080", want: Port{Name: "tcp-8080", Port: 8080, ServicePort: 8080, Protocol: "TCP"}},
+		{spec: "80:8080", want: Port{Name: "tcp-8080", Port: 8080, ServicePort: 80, Protocol: "TCP"}},
+		{spec: "web=80:8080", want: Port{Name: "web", Port: 8080, ServicePort: 80, Protocol: "TCP"}},
+		{spec: "53/udp", want: Port{Name: "udp-53", Port: 53, ServicePort: 53, Protocol: "UDP"}},
+		{spec: "9000/SCTP", want: Port{Name: "sctp-9000", Port: 9000, ServicePort: 9000, Protocol: "SCTP"}},
+		{spec: "http=80:8080/http", want: Port{Name: "http", Port: 8080, ServicePort: 80, Protocol: "TCP", AppProtocol: "http"}},
+		{spec: "50051/grpc", want: Port{Name: "grpc-50051", Port: 50051, ServicePort: 50051, Protocol: "TCP", AppProtocol: "grpc"}},
+		{spec: " 443/https ", want: Port{Name: "https-443", Port: 443, ServicePort: 443, Protocol: "TCP", AppProtocol: "https"}},
+		{spec: "8080/h2c", want: Port{Name: "h2c-8080", Port: 8080, ServicePort: 8080, Protocol: "TCP", AppProtocol: "h2c"}},
+		{spec: "8080/ws", want: Port{Name: "ws-8080", Port: 8080, ServicePort: 8080, Protocol: "TCP", AppProtocol: "ws"}},
+		{spec: "8443/wss", want: Port{Name: "wss-8443", Port: 8443, ServicePort: 8443, Protocol: "TCP", AppProtocol: "wss"}},
+		{spec: "8080/tpc", err: "unknown protocol tpc"},
+		{spec: "8080/", err: "empty protocol"},
+		{spec: "http", err: "invalid port"},
+		{spec: "", err: "invalid port"},
+		{spec: "x:8080", err: "invalid service port"},
+		{spec: "80:", err: "invalid port"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			port, err := ParsePort(test.spec)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("ParsePort(%q) error = %v, want one containing %q", test.spec, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePort(%q) returned error: %v", test.spec, err)
+			}
+			if port != test.want {
+				t.Errorf("ParsePort(%q) = %+v, want %+v", test.spec, port, test.want)
+			}
+		})
+	}
+}
+
+func TestValidatePorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []Port
+		err   string
+	}{
+		{name: "defaults", ports: DefaultPorts()},
+		{name: "none", ports: nil},
+		{
+			name: "same number on different protocols",
+			ports: []Port{
+				{Name: "dns-tcp", Port: 53, ServicePort: 53, Protocol: "TCP"},
+				{Name: "dns-udp", Port: 53, ServicePort: 53, Protocol: "UDP"},
+			},
+		},
+		{name: "port too low", ports: []Port{{Name: "web", Port: 0, ServicePort: 80, Protocol: "TCP"}}, err: "port 0 for web is out of range"},
+		{name: "port too high", ports: []Port{{Name: "web", Port: 65536, ServicePort: 80, Protocol: "TCP"}}, err: "out of range"},
+		{name: "service port out of range", ports: []Port{{Name: "web", Port: 80, ServicePort: 70000, Protocol: "TCP"}}, err: "service port 70000 for web is out of range"},
+		{name: "uppercase name", ports: []Port{{Name: "Web", Port: 80, ServicePort: 80, Protocol: "TCP"}}, err: "port name Web"},
+		{name: "long name", ports: []Port{{Name: "a-very-long-port-name", Port: 80, ServicePort: 80, Protocol: "TCP"}}, err: "no more than 15"},
+		{
+			name: "duplicate name",
+			ports: []Port{
+				{Name: "web", Port: 80, ServicePort: 80, Protocol: "TCP"},
+				{Name: "web", Port: 81, ServicePort: 81, Protocol: "TCP"},
+			},
+			err: "port name web is used more than once",
+		},
+		{
+			name: "duplicate service port",
+			ports: []Port{
+				{Name: "web", Port: 8080, ServicePort: 80, Protocol: "TCP"},
+				{Name: "admin", Port: 9090, ServicePort: 80, Protocol: "TCP"},
+			},
+			err: "service port 80/TCP is used more than once",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidatePorts(test.ports)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("ValidatePorts returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("ValidatePorts error = %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	ports, err := ParsePorts([]string{"http=80:8080/http", "grpc=50051/grpc"})
+	if err != nil {
+		t.Fatalf("ParsePorts returned error: %v", err)
+	}
+	if len(ports) != 2 || ports[0].Name != "http" || ports[1].Name != "grpc" {
+		t.Errorf("ParsePorts = %+v, want the http and grpc ports in order", ports)
+	}
+
+	if _, err := ParsePorts([]string{"8080", "8080"}); err == nil || !strings.Contains(err.Error(), "used more than once") {
+		t.Errorf("ParsePorts with duplicate ports error = %v, want a duplicate port error", err)
+	}
+	if _, err := ParsePorts([]string{"8080", "9090/tpc"}); err == nil || !strings.Contains(err.Error(), "unknown protocol") {
+		t.Errorf("ParsePorts with an unknown protocol error = %v, want an unknown protocol error", err)
+	}
+}
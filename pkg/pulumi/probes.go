@@ -0,0 +1,160 @@
+package pulumi
+
+import (
+	"fmt"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Probe types supported for checking the application container
+const (
+	ProbeHTTP = "http"
+	ProbeTCP  = "tcp"
+	ProbeGRPC = "grpc"
+	ProbeExec = "exec"
+	// ProbeNone disables a probe, including the default readiness probe
+	ProbeNone = "none"
+)
+
+// Probe checks the health of the application container
+// Port defaults to the primary port, which is the first one exposed
+type Probe struct {
+	Type    string
+	Path    string
+	Port    int
+	Service string
+	Command []string
+
+	InitialDelaySeconds int
+	PeriodSeconds       int
+	TimeoutSeconds      int
+	SuccessThreshold    int
+	FailureThreshold    int
+}
+
+// Probes are the liveness, readiness and startup probes for the application container
+type Probes struct {
+	Liveness  *Probe
+	Readiness *Probe
+	Startup   *Probe
+}
+
+// Validate checks each probe is complete and its values are usable
+func (p Probes) Validate() error {
+	if err := p.Liveness.validate("liveness", true); err != nil {
+		return err
+	}
+	if err := p.Readiness.validate("readiness", false); err != nil {
+		return err
+	}
+	return p.Startup.validate("startup", true)
+}
+
+func (p *Probe) validate(kind string, singleSuccess bool) error {
+	if p == nil {
+		return nil
+	}
+
+	switch p.Type {
+	case ProbeHTTP, ProbeTCP, ProbeGRPC, ProbeNone:
+	case ProbeExec:
+		if len(p.Command) == 0 {
+			return fmt.Errorf("%s probe must have a command", kind)
+		}
+	default:
+		return fmt.Errorf("unknown %s probe type %q, must be one of http, tcp, grpc, exec or none", kind, p.Type)
+	}
+
+	if p.Port < 0 || p.Port > 65535 {
+		return fmt.Errorf("%s probe port %d is out of range", kind, p.Port)
+	}
+
+	if p.InitialDelaySeconds < 0 || p.PeriodSeconds < 0 || p.TimeoutSeconds < 0 || p.SuccessThreshold < 0 || p.FailureThreshold < 0 {
+		return fmt.Errorf("%s probe delays and thresholds must not be negative", kind)
+	}
+
+	// Kubernetes only allows a success threshold other than 1 for readiness probes
+	if singleSuccess && p.SuccessThreshold > 1 {
+		return fmt.Errorf("%s probe success threshold must be 1", kind)
+	}
+
+	return nil
+}
+
+// defaultReadinessProbe checks the primary port in the way its protocol suggests
+// There's nothing sensible to check for UDP, so no probe is added
+func defaultReadinessProbe(primary Port) *Probe {
+	if primary.Protocol != "TCP" {
+		return nil
+	}
+
+	switch primary.AppProtocol {
+	case "", "http":
+		return &Probe{Type: ProbeHTTP, Path: "/"}
+	case "grpc":
+		return &Probe{Type: ProbeGRPC}
+	default:
+		return &Probe{Type: ProbeTCP}
+	}
+}
+
+// probeArgs converts a probe into the container spec, returning nil when the probe is disabled
+func (p *Probe) probeArgs(primary Port) corev1.ProbePtrInput {
+	if p == nil || p.Type == ProbeNone {
+		return nil
+	}
+
+	port := p.Port
+	if port == 0 {
+		port = primary.Port
+	}
+
+	probe := &corev1.ProbeArgs{}
+
+	switch p.Type {
+	case ProbeHTTP:
+		path := p.Path
+		if path == "" {
+			path = "/"
+		}
+		probe.HttpGet = &corev1.HTTPGetActionArgs{
+			Path: pulumi.String(path),
+			Port: pulumi.Int(port),
+		}
+	case ProbeTCP:
+		probe.TcpSocket = &corev1.TCPSocketActionArgs{
+			Port: pulumi.Int(port),
+		}
+	case ProbeGRPC:
+		grpc := &corev1.GRPCActionArgs{
+			Port: pulumi.Int(port),
+		}
+		if p.Service != "" {
+			grpc.Service = pulumi.String(p.Service)
+		}
+		probe.Grpc = grpc
+	case ProbeExec:
+		probe.Exec = &corev1.ExecActionArgs{
+			Command: pulumi.ToStringArray(p.Command),
+		}
+	}
+
+	if p.InitialDelaySeconds > 0 {
+		probe.InitialDelaySeconds = pulumi.Int(p.InitialDelaySeconds)
+	}
+	if p.PeriodSeconds > 0 {
+		probe.PeriodSeconds = pulumi.Int(p.PeriodSeconds)
+	}
+	if p.TimeoutSeconds > 0 {
+		probe.TimeoutSeconds = pulumi.Int(p.TimeoutSeconds)
+	}
+	if p.SuccessThreshold > 0 {
+		probe.SuccessThreshold = pulumi.Int(p.SuccessThreshold)
+	}
+	if p.FailureThreshold > 0 {
+		probe.FailureThreshold = pulumi.Int(p.FailureThreshold)
+	}
+
+	return probe
+}
@@ -1,19 +1,19 @@
 package pulumi
 
 import (
-	"encoding/base64"
+	"context"
 	"fmt"
-	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ecr"
+	"github.com/jaxxstorm/ploy/pkg/kube"
 	"github.com/pulumi/pulumi-docker/sdk/v3/go/docker"
 	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/apps/v1"
+	autoscalingv2 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/autoscaling/v2"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
 	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	networkingv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/networking/v1"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
-	log "github.com/sirupsen/logrus"
 )
 
 type PloyDeployment struct {
@@ -25,6 +25,139 @@ type PloyDeployment struct {
 type PloyDeploymentArgs struct {
 	Directory string
 	Nlb       bool
+	Ports     []Port
+	Replicas  *int
+	Resources Resources
+	Probes    Probes
+	Autoscale *Autoscale
+	Ingress   *Ingress
+	Registry  *Registry
+	Build     Build
+
+	// Expose is one of the Expose modes, and SourceRanges are the CIDRs allowed to reach a public app
+	Expose       string
+	SourceRanges []string
+
+	// Domains and TLS are managed with ploy domains and stored in their own stack config values
+	Domains []string `json:"-"`
+	TLS     *TLS     `json:"-"`
+
+	// Env holds the environment declared in ploy.yaml, which values set with ploy config override
+	Env map[string]string
+
+	// Config and Secrets are stored as individual stack config values rather than with the rest of the settings
+	Config  map[string]string `json:"-"`
+	Secrets map[string]string `json:"-"`
+	// Image is an already pushed image to roll out instead of building Directory
+	Image string `json:"-"`
+	// Prebuilt is set when Image was built outside of ploy, so there's no repository to manage
+	Prebuilt bool
+	// KeepRepository keeps the ECR repository of an app ploy built before it switched to prebuilt images,
+	// as deleting it would fail on, or delete, the images earlier releases roll back to
+	KeepRepository bool `json:"-"`
+	// Source is the code the image was built from, which also decides its tag
+	Source *Source
+	// ReleaseCommand runs as a Job with the new image before it's rolled out, like a database migration
+	ReleaseCommand []string
+	// AddressTimeout is how long to wait for the app's load balancer to get an address
+	AddressTimeout time.Duration `json:"-"`
+	// RestartedAt is when the app was last restarted with ploy restart, and is stored in its own stack config value
+	RestartedAt string `json:"-"`
+	// SkipAwait leaves waiting for the Deployment's rollout to the caller, rather than Pulumi
+	SkipAwait bool `json:"-"`
+}
+
+// Validate checks the deployment settings before any stack is touched, so impossible values are rejected early
+func (args *PloyDeploymentArgs) Validate() error {
+	if err := ValidatePorts(args.Ports); err != nil {
+		return err
+	}
+
+	if args.Replicas != nil && *args.Replicas < 0 {
+		return fmt.Errorf("replicas must not be negative, got %d", *args.Replicas)
+	}
+
+	for key := range args.Environment() {
+		if err := ValidateEnvName(key); err != nil {
+			return err
+		}
+	}
+	for key := range args.Secrets {
+		if err := ValidateEnvName(key); err != nil {
+			return err
+		}
+	}
+
+	if err := args.Ingress.Validate(); err != nil {
+		return err
+	}
+	if err := args.validateExposure(); err != nil {
+		return err
+	}
+	if args.Ingress != nil && args.ports()[0].Protocol != "TCP" {
+		return fmt.Errorf("ingress needs the primary port to use TCP")
+	}
+
+	for _, domain := range args.Domains {
+		if err := ValidateDomain(domain); err != nil {
+			return err
+		}
+	}
+	if err := args.validateTLS(); err != nil {
+		return err
+	}
+
+	if args.Prebuilt {
+		if err := ValidateImage(args.Image); err != nil {
+			return err
+		}
+		if args.Registry != nil {
+			return fmt.Errorf("prebuilt images are pulled from where they were pushed, a registry can't be set")
+		}
+	}
+	if err := args.Registry.Validate(); err != nil {
+		return err
+	}
+	if args.Image == "" {
+		if err := args.Build.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := args.Autoscale.Validate(); err != nil {
+		return err
+	}
+
+	if err := args.Probes.Validate(); err != nil {
+		return err
+	}
+
+	return args.Resources.Validate()
+}
+
+// ports returns the ports to expose, falling back to the defaults
+func (args *PloyDeploymentArgs) ports() []Port {
+	if len(args.Ports) == 0 {
+		return DefaultPorts()
+	}
+	return args.Ports
+}
+
+// PrimaryPort returns the port probes, ingress and generated Dockerfiles use, the first one exposed
+func (args *PloyDeploymentArgs) PrimaryPort() Port {
+	return args.ports()[0]
+}
+
+// Environment merges the declared environment with the values set with ploy config
+func (args *PloyDeploymentArgs) Environment() map[string]string {
+	env := make(map[string]string)
+	for key, value := range args.Env {
+		env[key] = value
+	}
+	for key, value := range args.Config {
+		env[key] = value
+	}
+	return env
 }
 
 func NewPloyDeployment(ctx *pulumi.Context, name string, args *PloyDeploymentArgs, opts ...pulumi.ResourceOption) (*PloyDeployment, error) {
@@ -36,49 +169,82 @@ func NewPloyDeployment(ctx *pulumi.Context, name string, args *PloyDeploymentArg
 		return nil, err
 	}
 
-	repo, err := ecr.NewRepository(ctx, name, &ecr.RepositoryArgs{})
-	if err != nil {
-		return nil, err
-	}
-
-	// retrieve the credentials from the ECR repo
-	repoCreds := repo.RegistryId.ApplyT(func(id string) ([]string, error) {
-		creds, err := ecr.GetCredentials(ctx, &ecr.GetCredentialsArgs{
-			RegistryId: id,
-		}, pulumi.Parent(ployDeployment))
+	// Prebuilt images are deployed as they are, so there's no repository to push to
+	var repo *imageRepository
+	if !args.Prebuilt || args.KeepRepository {
+		repo, err = newImageRepository(ctx, name, args.Registry, pulumi.Parent(ployDeployment))
 		if err != nil {
 			return nil, err
 		}
-		data, err := base64.StdEncoding.DecodeString(creds.AuthorizationToken)
+	}
+
+	// Images are only built when we aren't rolling out one that's already deployed
+	var imageDependencies []pulumi.Resource
+	if args.Image != "" {
+		ployDeployment.ImageName = pulumi.String(args.Image).ToStringOutput()
+	} else {
+		// images are tagged after their source, so unchanged code doesn't produce a new image
+		tag := fmt.Sprintf("%d", time.Now().Unix())
+		if args.Source != nil {
+			tag = args.Source.Tag()
+		}
+
+		// build the docker image
+		image, err := docker.NewImage(ctx, name, &docker.ImageArgs{
+			Build:     args.dockerBuild(),
+			ImageName: pulumi.Sprintf("%s:%s", repo.URL, tag),
+			Registry:  repo.imageRegistry(ctx, pulumi.Parent(ployDeployment)),
+		}, pulumi.Parent(ployDeployment))
+
 		if err != nil {
-			fmt.Println("error:", err)
 			return nil, err
 		}
 
-		return strings.Split(string(data), ":"), nil
-	}).(pulumi.StringArrayOutput)
+		ployDeployment.ImageName = image.ImageName
+		imageDependencies = append(imageDependencies, image)
+	}
 
-	repoUser := repoCreds.Index(pulumi.Int(0))
-	repoPass := repoCreds.Index(pulumi.Int(1))
+	ports := args.ports()
 
-	// build the docker image
-	image, err := docker.NewImage(ctx, name, &docker.ImageArgs{
-		Build: docker.DockerBuildArgs{
-			Context: pulumi.String(filepath.Join(args.Directory)),
-		},
-		ImageName: pulumi.Sprintf("%s:%d", repo.RepositoryUrl, pulumi.Int(time.Now().Unix())),
-		Registry: docker.ImageRegistryArgs{
-			Server:   repo.RepositoryUrl,
-			Username: repoUser,
-			Password: repoPass,
-		},
-	}, pulumi.Parent(ployDeployment))
+	// The first port is the primary one, which probes check unless they're told otherwise
+	primaryPort := ports[0]
+	readinessProbe := args.Probes.Readiness
+	if readinessProbe == nil {
+		readinessProbe = defaultReadinessProbe(primaryPort)
+	}
 
-	if err != nil {
-		return nil, err
+	containerPorts := corev1.ContainerPortArray{}
+	servicePorts := corev1.ServicePortArray{}
+	for _, port := range ports {
+		containerPorts = append(containerPorts, corev1.ContainerPortArgs{
+			Name:          pulumi.String(port.Name),
+			ContainerPort: pulumi.Int(port.Port),
+			Protocol:      pulumi.String(port.Protocol),
+		})
+
+		servicePort := corev1.ServicePortArgs{
+			Name:       pulumi.String(port.Name),
+			Port:       pulumi.Int(port.ServicePort),
+			TargetPort: pulumi.String(port.Name),
+			Protocol:   pulumi.String(port.Protocol),
+		}
+		if port.AppProtocol != "" {
+			servicePort.AppProtocol = pulumi.String(port.AppProtocol)
+		}
+		servicePorts = append(servicePorts, servicePort)
 	}
 
-	ployDeployment.ImageName = image.ImageName
+	// When autoscaling, the HorizontalPodAutoscaler owns the replica count, so we leave it unset
+	// and ignore changes to it, otherwise every update would fight the autoscaler
+	var replicas pulumi.IntPtrInput
+	deploymentOpts := []pulumi.ResourceOption{pulumi.DependsOn(imageDependencies)}
+	if args.Autoscale != nil {
+		deploymentOpts = append(deploymentOpts, pulumi.IgnoreChanges([]string{"spec.replicas"}))
+	} else if args.Replicas != nil {
+		replicas = pulumi.Int(*args.Replicas)
+	} else {
+		replicas = pulumi.Int(DefaultReplicas)
+	}
 
 	// Now we need to handle the Kubernetes of it all
 	labels := pulumi.StringMap{
@@ -96,52 +262,182 @@ func NewPloyDeployment(ctx *pulumi.Context, name string, args *PloyDeploymentArg
 		return nil, err
 	}
 
-	_, err = appsv1.NewDeployment(ctx, name, &appsv1.DeploymentArgs{
+	// Environment variables are rendered into a ConfigMap. It's auto-named so that any change
+	// creates a new ConfigMap, which changes the pod template and rolls out the Deployment
+	var envFrom corev1.EnvFromSourceArray
+	if environment := args.Environment(); len(environment) > 0 {
+		env := pulumi.StringMap{}
+		for key, value := range environment {
+			env[key] = pulumi.String(value)
+		}
+
+		configMap, err := corev1.NewConfigMap(ctx, name, &corev1.ConfigMapArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Namespace: namespace.Metadata.Name().Elem(),
+				Labels:    labels,
+			},
+			Data: env,
+		}, pulumi.Parent(namespace))
+		if err != nil {
+			return nil, err
+		}
+
+		envFrom = append(envFrom, corev1.EnvFromSourceArgs{
+			ConfigMapRef: &corev1.ConfigMapEnvSourceArgs{
+				Name: configMap.Metadata.Name(),
+			},
+		})
+	}
+
+	// Secrets work the same way, but their values are kept secret so they never appear in Pulumi's output
+	if len(args.Secrets) > 0 {
+		data := pulumi.StringMap{}
+		for key, value := range args.Secrets {
+			data[key] = pulumi.ToSecret(pulumi.String(value)).(pulumi.StringOutput)
+		}
+
+		secret, err := corev1.NewSecret(ctx, name, &corev1.SecretArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Namespace: namespace.Metadata.Name().Elem(),
+				Labels:    labels,
+			},
+			StringData: data,
+		}, pulumi.Parent(namespace))
+		if err != nil {
+			return nil, err
+		}
+
+		envFrom = append(envFrom, corev1.EnvFromSourceArgs{
+			SecretRef: &corev1.SecretEnvSourceArgs{
+				Name: secret.Metadata.Name(),
+			},
+		})
+	}
+
+	var imagePullSecrets corev1.LocalObjectReferenceArray
+	pullSecret, err := newPullSecret(ctx, name, args.Registry, namespace.Metadata.Name().Elem(), labels, pulumi.Parent(namespace))
+	if err != nil {
+		return nil, err
+	}
+	if pullSecret != nil {
+		imagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReferenceArgs{
+			Name: pullSecret.Metadata.Name(),
+		})
+	}
+
+	deploymentAnnotations := args.Source.annotations()
+	if args.SkipAwait {
+		deploymentAnnotations["pulumi.com/skipAwait"] = pulumi.String("true")
+	}
+
+	// the release command has to succeed before the Deployment is touched
+	releaseJob, err := newReleaseJob(ctx, name, args, ployDeployment.ImageName, envFrom, imagePullSecrets, namespace.Metadata.Name().Elem(), labels,
+		pulumi.Parent(namespace), pulumi.DependsOn(imageDependencies))
+	if err != nil {
+		return nil, err
+	}
+	if releaseJob != nil {
+		deploymentOpts = append(deploymentOpts, pulumi.DependsOn([]pulumi.Resource{releaseJob}))
+	}
+
+	// changing the pod template annotation is what rolls the pods, the same way kubectl rollout restart does
+	podAnnotations := pulumi.StringMap{}
+	if args.RestartedAt != "" {
+		podAnnotations["kubectl.kubernetes.io/restartedAt"] = pulumi.String(args.RestartedAt)
+	}
+
+	deployment, err := appsv1.NewDeployment(ctx, name, &appsv1.DeploymentArgs{
 		Metadata: &metav1.ObjectMetaArgs{
-			Name:      pulumi.String(name),
-			Namespace: namespace.Metadata.Name().Elem(),
-			Labels:    labels,
+			Name:        pulumi.String(name),
+			Namespace:   namespace.Metadata.Name().Elem(),
+			Labels:      labels,
+			Annotations: deploymentAnnotations,
 		},
 		Spec: appsv1.DeploymentSpecArgs{
 			Selector: &metav1.LabelSelectorArgs{
 				MatchLabels: labels,
 			},
-			Replicas: pulumi.Int(3),
+			Replicas: replicas,
 			Template: &corev1.PodTemplateSpecArgs{
 				Metadata: &metav1.ObjectMetaArgs{
-					Name:   pulumi.String(name),
-					Labels: labels,
+					Name:        pulumi.String(name),
+					Labels:      labels,
+					Annotations: podAnnotations,
 				},
 				Spec: &corev1.PodSpecArgs{
+					ImagePullSecrets: imagePullSecrets,
 					Containers: corev1.ContainerArray{
 						corev1.ContainerArgs{
-							Name:  pulumi.String("name"),
-							Image: image.ImageName,
-							Ports: corev1.ContainerPortArray{
-								&corev1.ContainerPortArgs{
-									ContainerPort: pulumi.Int(80), // FIXME make this configurable
-								},
-							},
+							Name:           pulumi.String("name"),
+							Image:          ployDeployment.ImageName,
+							EnvFrom:        envFrom,
+							Ports:          containerPorts,
+							Resources:      args.Resources.resourceRequirements(),
+							LivenessProbe:  args.Probes.Liveness.probeArgs(primaryPort),
+							ReadinessProbe: readinessProbe.probeArgs(primaryPort),
+							StartupProbe:   args.Probes.Startup.probeArgs(primaryPort),
 						},
 					},
 				},
 			},
 		},
-	}, pulumi.Parent(namespace), pulumi.DependsOn([]pulumi.Resource{image}))
+	}, append(deploymentOpts, pulumi.Parent(namespace))...)
 	if err != nil {
 		return nil, err
 	}
 
-	var serviceType pulumi.String
-	var annotations pulumi.StringMap
-	if args.Nlb {
-		serviceType = "NodePort"
-		annotations = pulumi.StringMap{
-			"service.beta.kubernetes.io/aws-load-balancer-type": pulumi.String("nlb-ip"),
+	if args.Autoscale != nil {
+		_, err = autoscalingv2.NewHorizontalPodAutoscaler(ctx, name, &autoscalingv2.HorizontalPodAutoscalerArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Name:      pulumi.String(name),
+				Namespace: namespace.Metadata.Name().Elem(),
+				Labels:    labels,
+			},
+			Spec: &autoscalingv2.HorizontalPodAutoscalerSpecArgs{
+				ScaleTargetRef: &autoscalingv2.CrossVersionObjectReferenceArgs{
+					ApiVersion: pulumi.String("apps/v1"),
+					Kind:       pulumi.String("Deployment"),
+					Name:       deployment.Metadata.Name().Elem(),
+				},
+				MinReplicas: pulumi.Int(args.Autoscale.Min),
+				MaxReplicas: pulumi.Int(args.Autoscale.Max),
+				Metrics:     args.Autoscale.metricSpecs(),
+			},
+		}, pulumi.Parent(namespace))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	serviceType, annotations := args.service()
+
+	if args.Ingress == nil && args.exposure() != ExposeInternal {
+		// external-dns points the app's domains at its load balancer, if it's running in the cluster
+		if len(args.Domains) > 0 {
+			annotations["external-dns.alpha.kubernetes.io/hostname"] = pulumi.String(strings.Join(args.Domains, ","))
+		}
+
+		// the load balancer terminates TLS with the ACM certificate and forwards to the primary port
+		if args.TLS != nil && args.TLS.CertificateArn != "" {
+			annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-cert"] = pulumi.String(args.TLS.CertificateArn)
+			annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-ports"] = pulumi.String("https")
+			annotations["service.beta.kubernetes.io/aws-load-balancer-backend-protocol"] = pulumi.String("http")
+			servicePorts = append(servicePorts, corev1.ServicePortArgs{
+				Name:       pulumi.String("https"),
+				Port:       pulumi.Int(443),
+				TargetPort: pulumi.String(primaryPort.Name),
+				Protocol:   pulumi.String("TCP"),
+			})
 		}
-	} else {
-		serviceType = "LoadBalancer"
-		annotations = pulumi.StringMap{}
+	}
+
+	serviceSpec := &corev1.ServiceSpecArgs{
+		Ports:    servicePorts,
+		Type:     serviceType,
+		Selector: labels,
+	}
+	if args.Ingress == nil && len(args.SourceRanges) > 0 {
+		serviceSpec.LoadBalancerSourceRanges = pulumi.ToStringArray(args.SourceRanges)
 	}
 
 	service, err := corev1.NewService(ctx, name, &corev1.ServiceArgs{
@@ -151,30 +447,58 @@ func NewPloyDeployment(ctx *pulumi.Context, name string, args *PloyDeploymentArg
 			Labels:      labels,
 			Annotations: annotations,
 		},
-		Spec: &corev1.ServiceSpecArgs{
-			Ports: corev1.ServicePortArray{
-				corev1.ServicePortArgs{
-					Port:       pulumi.Int(80),
-					TargetPort: pulumi.Int(80),
-				},
-			},
-			Type:     serviceType,
-			Selector: labels,
-		},
-	}, pulumi.Parent(namespace), pulumi.DependsOn([]pulumi.Resource{image}))
+		Spec: serviceSpec,
+	}, pulumi.Parent(namespace), pulumi.DependsOn(imageDependencies))
 	if err != nil {
 		return nil, err
 	}
 
-	ctx.Export("address", service.Status.ApplyT(func(status *corev1.ServiceStatus) *string {
-		ingress := status.LoadBalancer.Ingress[0]
-		if ingress.Hostname != nil {
-			log.Infof("Your service is available at: %v", *ingress.Hostname)
-			return ingress.Hostname
+	var host pulumi.StringOutput
+	if args.Ingress != nil {
+		ingress, err := newIngress(ctx, name, args, service, primaryPort, labels, pulumi.Parent(namespace))
+		if err != nil {
+			return nil, err
 		}
-		log.Info("Your service is available at: %v", *ingress.Ip)
-		return ingress.Ip
-	}))
+
+		// the app is reached by its hosts if it has any, otherwise through the controller's load balancer
+		host = ingress.Status.ApplyT(func(status *networkingv1.IngressStatus) string {
+			if hosts := args.hosts(); len(hosts) > 0 {
+				return hosts[0]
+			}
+			var lb *corev1.LoadBalancerStatus
+			if status != nil {
+				lb = status.LoadBalancer
+			}
+			return args.resolveHost(ctx, name, lb, func(waitCtx context.Context, client *kube.Client) (string, error) {
+				return client.WaitForIngressAddress(waitCtx, name, name)
+			})
+		}).(pulumi.StringOutput)
+	} else if args.exposure() == ExposeInternal {
+		// internal apps are only reachable inside the cluster, through the Service's DNS name
+		host = pulumi.String(internalHost(name)).ToStringOutput()
+	} else {
+		host = service.Status.ApplyT(func(status *corev1.ServiceStatus) string {
+			if len(args.Domains) > 0 {
+				return args.Domains[0]
+			}
+			var lb *corev1.LoadBalancerStatus
+			if status != nil {
+				lb = status.LoadBalancer
+			}
+			// NodePort Services for an NLB never get a load balancer status, so there's nothing to wait for
+			if serviceType != "LoadBalancer" {
+				return args.resolveHost(ctx, name, lb, nil)
+			}
+			return args.resolveHost(ctx, name, lb, func(waitCtx context.Context, client *kube.Client) (string, error) {
+				return client.WaitForServiceAddress(waitCtx, name, name)
+			})
+		}).(pulumi.StringOutput)
+	}
+	args.exportEndpoints(ctx, name, host)
+
+	ctx.Export("scheme", pulumi.String(args.scheme()))
+	ctx.Export("expose", pulumi.String(args.exposure()))
+	args.Source.export(ctx)
 
 	ctx.RegisterResourceOutputs(ployDeployment, pulumi.Map{
 		"ImageName": ployDeployment.ImageName,
@@ -183,17 +507,17 @@ func NewPloyDeployment(ctx *pulumi.Context, name string, args *PloyDeploymentArg
 	return ployDeployment, nil
 }
 
-func Deploy(name string, directory string, nlb bool) pulumi.RunFunc {
+func Deploy(name string, args *PloyDeploymentArgs) pulumi.RunFunc {
 	return func(ctx *pulumi.Context) error {
 
-		_, err := NewPloyDeployment(ctx, name, &PloyDeploymentArgs{
-			Directory: directory,
-			Nlb:       nlb,
-		})
+		deployment, err := NewPloyDeployment(ctx, name, args)
 		if err != nil {
 			return err
 		}
 
+		// the deployed image is read back when redeploying without a rebuild
+		ctx.Export("ImageName", deployment.ImageName)
+
 		return nil
 	}
 
@@ -0,0 +1,264 @@
+package pulumi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ecr"
+	"github.com/pulumi/pulumi-docker/sdk/v3/go/docker"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Registry types ploy can push images to
+const (
+	RegistryECR       = "ecr"
+	RegistryDockerHub = "dockerhub"
+	RegistryGHCR      = "ghcr"
+	RegistryGeneric   = "generic"
+	RegistryLocal     = "local"
+)
+
+// DefaultLocalRegistry is where a plain local registry, such as the one used with kind, listens
+const DefaultLocalRegistry = "localhost:5000"
+
+// dockerHubServer is the key Docker uses for Docker Hub credentials
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// Registry is where the app's images are pushed and pulled from
+// Namespace is the user, organization or path the app's repository sits under
+// Without a username, pushes use the credentials docker is already logged in with
+// A username without a password is allowed, as the password may already be stored with the app
+type Registry struct {
+	Type      string
+	Server    string
+	Namespace string
+	Username  string
+	// Password is stored as a stack secret rather than with the rest of the settings
+	Password string `json:"-"`
+}
+
+// ParseRegistry parses a registry as given to --registry, one of ecr, local or an address
+// such as ghcr.io/acme, docker.io/acme, localhost:5000 or registry.example.com/team
+func ParseRegistry(spec string) (*Registry, error) {
+	spec = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(spec, "https://"), "http://"), "/")
+
+	switch spec {
+	case "", RegistryECR:
+		return &Registry{Type: RegistryECR}, nil
+	case RegistryLocal:
+		return &Registry{Type: RegistryLocal, Server: DefaultLocalRegistry}, nil
+	}
+
+	server := spec
+	var namespace string
+	if i := strings.Index(spec, "/"); i >= 0 {
+		server, namespace = spec[:i], spec[i+1:]
+	}
+
+	registry := &Registry{Server: server, Namespace: namespace}
+	switch {
+	case server == "docker.io" || server == "index.docker.io" || server == RegistryDockerHub:
+		registry.Type = RegistryDockerHub
+		registry.Server = "docker.io"
+	case server == "ghcr.io" || server == RegistryGHCR:
+		registry.Type = RegistryGHCR
+		registry.Server = "ghcr.io"
+	case server == "localhost" || strings.HasPrefix(server, "localhost:") || strings.HasPrefix(server, "127.0.0.1"):
+		registry.Type = RegistryLocal
+	case strings.ContainsAny(server, ".:"):
+		registry.Type = RegistryGeneric
+	default:
+		return nil, fmt.Errorf("invalid registry %q: must be ecr, local or a registry address such as ghcr.io/acme", spec)
+	}
+
+	return registry, registry.Validate()
+}
+
+// Validate checks the registry has everything needed to push to it
+func (r *Registry) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	switch r.Type {
+	case RegistryECR:
+		if r.Username != "" || r.Password != "" {
+			return fmt.Errorf("ECR credentials come from your AWS account and can't be set")
+		}
+		return nil
+	case RegistryDockerHub, RegistryGHCR:
+		if r.Namespace == "" {
+			return fmt.Errorf("%s images must be pushed under a user or organization, e.g. %s/acme", r.Type, r.Server)
+		}
+	case RegistryGeneric, RegistryLocal:
+		if r.Server == "" {
+			return fmt.Errorf("%s registry needs a server address", r.Type)
+		}
+	default:
+		return fmt.Errorf("unknown registry type %q, must be one of ecr, dockerhub, ghcr, generic or local", r.Type)
+	}
+
+	if r.Password != "" && r.Username == "" {
+		return fmt.Errorf("registry password needs a username")
+	}
+
+	return nil
+}
+
+// LoadDockerConfig reads the registry's credentials from a docker config file, such as ~/.docker/config.json
+// Only credentials stored in the file itself can be used, not ones held by a credential helper
+func (r *Registry) LoadDockerConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading docker config: %v", err)
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("error reading docker config %s: %v", path, err)
+	}
+
+	auth, ok := config.Auths[r.authServer()]
+	if !ok {
+		auth, ok = config.Auths[r.Server]
+	}
+	if !ok || auth.Auth == "" {
+		return fmt.Errorf("no credentials for %s found in %s, they may be held by a credential helper", r.Server, path)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+	if err != nil {
+		return fmt.Errorf("error reading credentials for %s from %s: %v", r.Server, path, err)
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid credentials for %s in %s", r.Server, path)
+	}
+	r.Username, r.Password = parts[0], parts[1]
+
+	return nil
+}
+
+// authServer returns the key docker stores the registry's credentials under
+func (r *Registry) authServer() string {
+	if r.Type == RegistryDockerHub {
+		return dockerHubServer
+	}
+	return r.Server
+}
+
+// imageRepository is where the app's images are pushed
+type imageRepository struct {
+	URL pulumi.StringOutput
+
+	registry *Registry
+	ecr      *ecr.Repository
+}
+
+// newImageRepository creates an ECR repository when that's the registry in use, other registries
+// create repositories on the first push, so there's nothing to manage for them
+func newImageRepository(ctx *pulumi.Context, name string, registry *Registry, opts ...pulumi.ResourceOption) (*imageRepository, error) {
+	if registry == nil {
+		registry = &Registry{Type: RegistryECR}
+	}
+
+	if registry.Type == RegistryECR {
+		repo, err := ecr.NewRepository(ctx, name, &ecr.RepositoryArgs{}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &imageRepository{URL: repo.RepositoryUrl, registry: registry, ecr: repo}, nil
+	}
+
+	url := registry.Server
+	if registry.Namespace != "" {
+		url = fmt.Sprintf("%s/%s", url, registry.Namespace)
+	}
+	url = fmt.Sprintf("%s/%s", url, name)
+
+	return &imageRepository{URL: pulumi.String(url).ToStringOutput(), registry: registry}, nil
+}
+
+// imageRegistry returns the credentials to push with
+// Without credentials the server is left empty, so docker pushes with the login it already has
+func (r *imageRepository) imageRegistry(ctx *pulumi.Context, opts ...pulumi.InvokeOption) docker.ImageRegistryArgs {
+	if r.ecr != nil {
+		// retrieve the credentials from the ECR repo
+		repoCreds := r.ecr.RegistryId.ApplyT(func(id string) ([]string, error) {
+			creds, err := ecr.GetCredentials(ctx, &ecr.GetCredentialsArgs{
+				RegistryId: id,
+			}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			data, err := base64.StdEncoding.DecodeString(creds.AuthorizationToken)
+			if err != nil {
+				return nil, err
+			}
+
+			return strings.Split(string(data), ":"), nil
+		}).(pulumi.StringArrayOutput)
+
+		return docker.ImageRegistryArgs{
+			Server:   r.URL,
+			Username: repoCreds.Index(pulumi.Int(0)),
+			Password: repoCreds.Index(pulumi.Int(1)),
+		}
+	}
+
+	if r.registry.Username == "" {
+		return docker.ImageRegistryArgs{
+			Server:   pulumi.String(""),
+			Username: pulumi.String(""),
+			Password: pulumi.String(""),
+		}
+	}
+
+	return docker.ImageRegistryArgs{
+		Server:   pulumi.String(r.registry.Server),
+		Username: pulumi.String(r.registry.Username),
+		Password: pulumi.ToSecret(pulumi.String(r.registry.Password)).(pulumi.StringOutput),
+	}
+}
+
+// newPullSecret stores the registry credentials in the app's namespace, so the cluster can pull its images
+// ECR images are pulled with the nodes' IAM role and registries without credentials are assumed to be public
+func newPullSecret(ctx *pulumi.Context, name string, registry *Registry, namespace pulumi.StringInput, labels pulumi.StringMap, opts ...pulumi.ResourceOption) (*corev1.Secret, error) {
+	if registry == nil || registry.Type == RegistryECR || registry.Username == "" {
+		return nil, nil
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", registry.Username, registry.Password)))
+	config, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			registry.authServer(): map[string]string{
+				"username": registry.Username,
+				"password": registry.Password,
+				"auth":     auth,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return corev1.NewSecret(ctx, fmt.Sprintf("%s-registry", name), &corev1.SecretArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Type: pulumi.String("kubernetes.io/dockerconfigjson"),
+		StringData: pulumi.StringMap{
+			".dockerconfigjson": pulumi.ToSecret(pulumi.String(string(config))).(pulumi.StringOutput),
+		},
+	}, opts...)
+}
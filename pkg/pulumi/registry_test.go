@@ -0,0 +1,81 @@
+package pulumi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRegistry(t *testing.T) {
+	tests := []struct {
+		spec string
+		want *Registry
+		err  string
+	}{
+		{spec: "", want: &Registry{Type: RegistryECR}},
+		{spec: "ecr", want: &Registry{Type: RegistryECR}},
+		{spec: "local", want: &Registry{Type: RegistryLocal, Server: DefaultLocalRegistry}},
+		{spec: "localhost:5001", want: &Registry{Type: RegistryLocal, Server: "localhost:5001"}},
+		{spec: "127.0.0.1:5000/dev", want: &Registry{Type: RegistryLocal, Server: "127.0.0.1:5000", Namespace: "dev"}},
+		{spec: "ghcr.io/acme", want: &Registry{Type: RegistryGHCR, Server: "ghcr.io", Namespace: "acme"}},
+		{spec: "https://ghcr.io/acme/", want: &Registry{Type: RegistryGHCR, Server: "ghcr.io", Namespace: "acme"}},
+		{spec: "docker.io/acme", want: &Registry{Type: RegistryDockerHub, Server: "docker.io", Namespace: "acme"}},
+		{spec: "index.docker.io/acme", want: &Registry{Type: RegistryDockerHub, Server: "docker.io", Namespace: "acme"}},
+		{spec: "dockerhub/acme", want: &Registry{Type: RegistryDockerHub, Server: "docker.io", Namespace: "acme"}},
+		{spec: "registry.example.com/team/apps", want: &Registry{Type: RegistryGeneric, Server: "registry.example.com", Namespace: "team/apps"}},
+		{spec: "registry:5000", want: &Registry{Type: RegistryGeneric, Server: "registry:5000"}},
+		{spec: "ghcr.io", err: "ghcr images must be pushed under a user or organization"},
+		{spec: "docker.io", err: "dockerhub images must be pushed under a user or organization"},
+		{spec: "quay", err: "invalid registry"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			registry, err := ParseRegistry(test.spec)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("ParseRegistry(%q) error = %v, want one containing %q", test.spec, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRegistry(%q) returned error: %v", test.spec, err)
+			}
+			if !reflect.DeepEqual(registry, test.want) {
+				t.Errorf("ParseRegistry(%q) = %+v, want %+v", test.spec, registry, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry *Registry
+		err      string
+	}{
+		{name: "none", registry: nil},
+		{name: "ecr", registry: &Registry{Type: RegistryECR}},
+		{name: "ghcr with credentials", registry: &Registry{Type: RegistryGHCR, Server: "ghcr.io", Namespace: "acme", Username: "bot", Password: "token"}},
+		{name: "username without a stored password", registry: &Registry{Type: RegistryGeneric, Server: "registry.example.com", Username: "bot"}},
+		{name: "ecr credentials", registry: &Registry{Type: RegistryECR, Username: "bot"}, err: "ECR credentials come from your AWS account"},
+		{name: "generic without a server", registry: &Registry{Type: RegistryGeneric}, err: "generic registry needs a server address"},
+		{name: "password without a username", registry: &Registry{Type: RegistryLocal, Server: "localhost:5000", Password: "secret"}, err: "password needs a username"},
+		{name: "unknown type", registry: &Registry{Type: "quay"}, err: "unknown registry type"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.registry.Validate()
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("Validate returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Validate error = %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
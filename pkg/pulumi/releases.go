@@ -0,0 +1,179 @@
+package pulumi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// releaseKey holds the release being rolled out, so each update in the stack history describes itself
+const releaseKey = "ploy:release"
+
+// Kinds of release, recorded with every update
+const (
+	ReleaseDeploy   = "deploy"
+	ReleaseRollback = "rollback"
+	ReleaseConfig   = "config"
+)
+
+// Release is a single update of an app
+// Images built by ploy are only known once the update finishes, so they're recorded by the next
+// release as its PreviousImage, or read from the stack outputs for the latest one
+type Release struct {
+	Version int    `json:"-"`
+	Time    string `json:"-"`
+	Result  string `json:"-"`
+
+	Kind          string
+	Description   string `json:",omitempty"`
+	Image         string `json:",omitempty"`
+	PreviousImage string `json:",omitempty"`
+	Source        *Source
+	DeployedBy    string
+	RollbackOf    int `json:",omitempty"`
+	// Replaced is the release an automatic rollback replaced because its rollout wasn't healthy
+	Replaced int `json:",omitempty"`
+
+	// Settings are the deployment settings the release was rolled out with
+	Settings *PloyDeploymentArgs `json:"-"`
+}
+
+// Releases returns an app's releases from its stack history, newest first
+// Updates made before releases were recorded are left out
+func Releases(ctx context.Context, stack auto.Stack) ([]Release, error) {
+	history, err := stack.History(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error reading history: %v", err)
+	}
+
+	var releases []Release
+	for _, update := range history {
+		if update.Kind != "update" {
+			continue
+		}
+		value, ok := update.Config[releaseKey]
+		if !ok {
+			continue
+		}
+
+		release := Release{}
+		if err := json.Unmarshal([]byte(value.Value), &release); err != nil {
+			return nil, fmt.Errorf("error reading release %d: %v", update.Version, err)
+		}
+		release.Version = update.Version
+		release.Time = update.StartTime
+		release.Result = update.Result
+
+		if value, ok := update.Config[deploymentKey]; ok {
+			release.Settings = &PloyDeploymentArgs{}
+			if err := json.Unmarshal([]byte(value.Value), release.Settings); err != nil {
+				return nil, fmt.Errorf("error reading settings of release %d: %v", update.Version, err)
+			}
+		}
+
+		releases = append(releases, release)
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Version > releases[j].Version
+	})
+
+	// fill in the images that were built during a release
+	outputs, err := stack.Outputs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no stack outputs found: %v", err)
+	}
+	current, _ := outputs["ImageName"].Value.(string)
+	for i := range releases {
+		if releases[i].Image != "" || releases[i].Result != "succeeded" {
+			continue
+		}
+		if i == 0 {
+			releases[i].Image = current
+		} else {
+			releases[i].Image = releases[i-1].PreviousImage
+		}
+	}
+
+	// Pulumi reports releases whose rollout was rolled back as succeeded, as the update itself was
+	failed := make(map[int]bool)
+	for _, release := range releases {
+		if release.Replaced != 0 {
+			failed[release.Replaced] = true
+		}
+	}
+	for i := range releases {
+		if failed[releases[i].Version] {
+			releases[i].Result = "failed"
+		}
+	}
+
+	return releases, nil
+}
+
+// PreviousRelease returns the most recent successful release that rolled out a different image to the current one
+func PreviousRelease(releases []Release, current string) *Release {
+	for i := range releases {
+		if releases[i].Result == "succeeded" && releases[i].Image != "" && releases[i].Image != current {
+			return &releases[i]
+		}
+	}
+	return nil
+}
+
+// Rollback rolls out the image of an earlier release without rebuilding it, keeping the app's current settings
+// replaced is the release being rolled back because its rollout failed, or 0 when rolling back by hand
+func Rollback(ctx context.Context, stack auto.Stack, name string, target *Release, replaced int, description string, verbose bool) error {
+	args, err := LoadDeploymentArgs(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	args.Image = target.Image
+	args.Source = target.Source
+
+	if err := args.Validate(); err != nil {
+		return fmt.Errorf("invalid deployment settings: %v", err)
+	}
+
+	return Update(ctx, stack, name, args, &Release{
+		Kind:        ReleaseRollback,
+		Description: description,
+		RollbackOf:  target.Version,
+		Replaced:    replaced,
+	}, verbose)
+}
+
+// recordRelease stores the release about to be rolled out in the stack config
+func recordRelease(ctx context.Context, stack auto.Stack, args *PloyDeploymentArgs, release *Release) error {
+	release.Image = args.Image
+	release.Source = args.Source
+	release.DeployedBy = deployer()
+
+	// the image deployed by the last update, which is only known now that it's finished
+	if outputs, err := stack.Outputs(ctx); err == nil {
+		release.PreviousImage, _ = outputs["ImageName"].Value.(string)
+	}
+
+	data, err := json.Marshal(release)
+	if err != nil {
+		return err
+	}
+	return stack.SetConfig(ctx, releaseKey, auto.ConfigValue{Value: string(data)})
+}
+
+// deployer returns who is rolling out a release, which can be set with PLOY_USER, for example in CI
+func deployer() string {
+	if name := os.Getenv("PLOY_USER"); name != "" {
+		return name
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
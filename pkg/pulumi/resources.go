@@ -0,0 +1,88 @@
+package pulumi
+
+import (
+	"fmt"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultReplicas is the number of pods run when no replica count is given
+const DefaultReplicas = 3
+
+// Resources are the compute resources requested by, and the limits enforced on, the application container
+// Values use Kubernetes quantity notation, such as 250m or 0.5 for CPU and 128Mi or 1Gi for memory
+type Resources struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+}
+
+// Validate checks the quantities parse and that no request is larger than its limit
+func (r Resources) Validate() error {
+	if err := validateQuantities("cpu", r.CPURequest, r.CPULimit); err != nil {
+		return err
+	}
+	return validateQuantities("memory", r.MemoryRequest, r.MemoryLimit)
+}
+
+func validateQuantities(kind string, request string, limit string) error {
+	var requestQuantity, limitQuantity resource.Quantity
+	var err error
+
+	if request != "" {
+		requestQuantity, err = resource.ParseQuantity(request)
+		if err != nil {
+			return fmt.Errorf("invalid %s request %q: %v", kind, request, err)
+		}
+		if requestQuantity.Sign() <= 0 {
+			return fmt.Errorf("%s request %s must be greater than zero", kind, request)
+		}
+	}
+
+	if limit != "" {
+		limitQuantity, err = resource.ParseQuantity(limit)
+		if err != nil {
+			return fmt.Errorf("invalid %s limit %q: %v", kind, limit, err)
+		}
+		if limitQuantity.Sign() <= 0 {
+			return fmt.Errorf("%s limit %s must be greater than zero", kind, limit)
+		}
+	}
+
+	if request != "" && limit != "" && requestQuantity.Cmp(limitQuantity) > 0 {
+		return fmt.Errorf("%s request %s is larger than its limit %s", kind, request, limit)
+	}
+
+	return nil
+}
+
+// resourceRequirements converts the resources into the container spec, returning nil when nothing is set
+func (r Resources) resourceRequirements() corev1.ResourceRequirementsPtrInput {
+	requests := pulumi.StringMap{}
+	limits := pulumi.StringMap{}
+
+	if r.CPURequest != "" {
+		requests["cpu"] = pulumi.String(r.CPURequest)
+	}
+	if r.MemoryRequest != "" {
+		requests["memory"] = pulumi.String(r.MemoryRequest)
+	}
+	if r.CPULimit != "" {
+		limits["cpu"] = pulumi.String(r.CPULimit)
+	}
+	if r.MemoryLimit != "" {
+		limits["memory"] = pulumi.String(r.MemoryLimit)
+	}
+
+	if len(requests) == 0 && len(limits) == 0 {
+		return nil
+	}
+
+	return &corev1.ResourceRequirementsArgs{
+		Requests: requests,
+		Limits:   limits,
+	}
+}
@@ -0,0 +1,71 @@
+package pulumi
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Source describes the code an image is built from, so a deployment can be traced back to it
+// Digest is a content digest of the build context, used to tell uncommitted changes apart
+type Source struct {
+	Commit string
+	Branch string
+	Author string
+	Dirty  bool
+	Digest string
+}
+
+// Tag returns a deterministic image tag, the commit with a dirty suffix for uncommitted changes
+// Outside of a git repository, the build context's digest is used instead
+func (s *Source) Tag() string {
+	switch {
+	case s.Commit != "" && s.Dirty:
+		return fmt.Sprintf("%s-dirty-%s", short(s.Commit), short(s.Digest))
+	case s.Commit != "":
+		return short(s.Commit)
+	default:
+		return fmt.Sprintf("sha256-%s", short(s.Digest))
+	}
+}
+
+// annotations records the source on the Deployment
+func (s *Source) annotations() pulumi.StringMap {
+	annotations := pulumi.StringMap{}
+	if s == nil {
+		return annotations
+	}
+
+	values := map[string]string{
+		"app.getploy.io/commit": s.Commit,
+		"app.getploy.io/branch": s.Branch,
+		"app.getploy.io/author": s.Author,
+	}
+	for key, value := range values {
+		if value != "" {
+			annotations[key] = pulumi.String(value)
+		}
+	}
+	if s.Commit != "" && s.Dirty {
+		annotations["app.getploy.io/dirty"] = pulumi.String("true")
+	}
+	return annotations
+}
+
+// export records the source as stack outputs
+func (s *Source) export(ctx *pulumi.Context) {
+	if s == nil {
+		return
+	}
+	ctx.Export("commit", pulumi.String(s.Commit))
+	ctx.Export("branch", pulumi.String(s.Branch))
+	ctx.Export("author", pulumi.String(s.Author))
+}
+
+// short truncates a hash to the length git uses for abbreviated commits
+func short(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,27 @@
+package pulumi
+
+import "testing"
+
+func TestSourceTag(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	digest := "fedcba9876543210fedcba9876543210"
+
+	tests := []struct {
+		name   string
+		source Source
+		want   string
+	}{
+		{name: "commit", source: Source{Commit: commit, Digest: digest}, want: "0123456789ab"},
+		{name: "dirty commit", source: Source{Commit: commit, Dirty: true, Digest: digest}, want: "0123456789ab-dirty-fedcba987654"},
+		{name: "outside git", source: Source{Digest: digest}, want: "sha256-fedcba987654"},
+		{name: "short commit", source: Source{Commit: "abc123"}, want: "abc123"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if tag := test.source.Tag(); tag != test.want {
+				t.Errorf("Tag() = %q, want %q", tag, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package pulumi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+const (
+	// deploymentKey holds the settings used by the last `ploy up`, so other commands can redeploy without them
+	deploymentKey = "ploy:deployment"
+	// domainsKey and tlsKey hold the domains and certificate settings managed with ploy domains
+	domainsKey = "ploy:domains"
+	tlsKey     = "ploy:tls"
+	// registryPasswordKey holds the password for the app's registry, encrypted like any other secret
+	registryPasswordKey = "ploy:registryPassword"
+	// replicasKey holds the replica count set with ploy scale, which takes precedence over ploy.yaml
+	replicasKey = "ploy:replicas"
+	// restartedAtKey holds when the app was last restarted with ploy restart, so later updates don't restart it again
+	restartedAtKey = "ploy:restartedAt"
+	// envNamespace is the stack config namespace holding the app's environment variables
+	envNamespace = "env"
+	// secretNamespace is the stack config namespace holding the app's encrypted secrets
+	secretNamespace = "secret"
+)
+
+// SelectStack selects the stack of an existing ploy app and restores the config used by its last update
+// Stack config only lives in a temporary workspace, so it has to be pulled back from the backend every time
+func SelectStack(ctx context.Context, org string, name string) (auto.Stack, error) {
+	stackName := auto.FullyQualifiedStackName(org, "ploy", name)
+	stack, err := auto.SelectStackInlineSource(ctx, stackName, "ploy", nil)
+	if err != nil {
+		return stack, fmt.Errorf("failed to select app %s: %v", name, err)
+	}
+
+	_, err = stack.RefreshConfig(ctx)
+	if err != nil {
+		return stack, fmt.Errorf("failed to retrieve config for app %s, has it been deployed?: %v", name, err)
+	}
+
+	return stack, nil
+}
+
+// LoadDeploymentArgs rebuilds the deployment settings of an app from its stack config and outputs
+// The currently deployed image is reused, so the app can be rolled out again without a rebuild
+func LoadDeploymentArgs(ctx context.Context, stack auto.Stack) (*PloyDeploymentArgs, error) {
+	value, err := stack.GetConfig(ctx, deploymentKey)
+	if err != nil {
+		return nil, fmt.Errorf("no deployment settings found, run ploy up first: %v", err)
+	}
+
+	args := &PloyDeploymentArgs{}
+	if err := json.Unmarshal([]byte(value.Value), args); err != nil {
+		return nil, fmt.Errorf("error reading deployment settings: %v", err)
+	}
+
+	if err := LoadConfig(ctx, stack, args); err != nil {
+		return nil, err
+	}
+
+	outputs, err := stack.Outputs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no stack outputs found: %v", err)
+	}
+	image, ok := outputs["ImageName"].Value.(string)
+	if !ok || image == "" {
+		return nil, fmt.Errorf("no deployed image found, run ploy up first")
+	}
+	args.Image = image
+
+	return args, nil
+}
+
+// LoadConfig fills in the environment variables and secrets stored in the stack config
+func LoadConfig(ctx context.Context, stack auto.Stack, args *PloyDeploymentArgs) error {
+	config, err := stack.GetAllConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading stack config: %v", err)
+	}
+
+	env := make(map[string]string)
+	secrets := make(map[string]string)
+	for key, value := range config {
+		if name := strings.TrimPrefix(key, envNamespace+":"); name != key {
+			env[name] = value.Value
+		}
+		if name := strings.TrimPrefix(key, secretNamespace+":"); name != key && value.Secret {
+			secrets[name] = value.Value
+		}
+	}
+	args.Config = env
+	args.Secrets = secrets
+
+	if value, ok := config[domainsKey]; ok {
+		if err := json.Unmarshal([]byte(value.Value), &args.Domains); err != nil {
+			return fmt.Errorf("error reading domains: %v", err)
+		}
+	}
+
+	if value, ok := config[tlsKey]; ok {
+		args.TLS = &TLS{}
+		if err := json.Unmarshal([]byte(value.Value), args.TLS); err != nil {
+			return fmt.Errorf("error reading tls settings: %v", err)
+		}
+	}
+
+	if value, ok := config[replicasKey]; ok {
+		replicas, err := strconv.Atoi(value.Value)
+		if err != nil {
+			return fmt.Errorf("error reading replicas: %v", err)
+		}
+		args.Replicas = &replicas
+	}
+
+	if value, ok := config[restartedAtKey]; ok {
+		args.RestartedAt = value.Value
+	}
+
+	// a password given for this run takes precedence over the stored one
+	if value, ok := config[registryPasswordKey]; ok && args.Registry != nil && args.Registry.Password == "" {
+		args.Registry.Password = value.Value
+	}
+
+	return nil
+}
+
+// SaveDomains stores the app's domains and certificate settings in the stack config
+func SaveDomains(ctx context.Context, stack auto.Stack, domains []string, tls *TLS) error {
+	if err := saveJSON(ctx, stack, domainsKey, domains, len(domains) == 0); err != nil {
+		return fmt.Errorf("error saving domains: %v", err)
+	}
+	if err := saveJSON(ctx, stack, tlsKey, tls, tls == nil); err != nil {
+		return fmt.Errorf("error saving tls settings: %v", err)
+	}
+	return nil
+}
+
+// SaveReplicas stores the replica count set with ploy scale, or removes it so ploy.yaml decides again
+func SaveReplicas(ctx context.Context, stack auto.Stack, replicas *int) error {
+	if replicas == nil {
+		return removeConfig(ctx, stack, replicasKey)
+	}
+	return stack.SetConfig(ctx, replicasKey, auto.ConfigValue{Value: strconv.Itoa(*replicas)})
+}
+
+// saveJSON stores a value as JSON in the stack config, or removes the key if the value is empty
+func saveJSON(ctx context.Context, stack auto.Stack, key string, value interface{}, empty bool) error {
+	if empty {
+		return removeConfig(ctx, stack, key)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return stack.SetConfig(ctx, key, auto.ConfigValue{Value: string(data)})
+}
+
+// removeConfig removes a key from the stack config, if it's set
+func removeConfig(ctx context.Context, stack auto.Stack, key string) error {
+	config, err := stack.GetAllConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := config[key]; !ok {
+		return nil
+	}
+	return stack.RemoveConfig(ctx, key)
+}
+
+// saveRegistryPassword keeps the registry password as a stack secret, so redeploys can recreate the pull secret
+func saveRegistryPassword(ctx context.Context, stack auto.Stack, registry *Registry) error {
+	if registry == nil || registry.Password == "" {
+		return removeConfig(ctx, stack, registryPasswordKey)
+	}
+	return stack.SetConfig(ctx, registryPasswordKey, auto.ConfigValue{Value: registry.Password, Secret: true})
+}
+
+// EnvKey returns the stack config key used to store an environment variable
+func EnvKey(name string) string {
+	return fmt.Sprintf("%s:%s", envNamespace, name)
+}
+
+// SecretKey returns the stack config key used to store a secret
+func SecretKey(name string) string {
+	return fmt.Sprintf("%s:%s", secretNamespace, name)
+}
+
+// Redeploy rolls out an app again with its saved settings and currently deployed image
+// The description says what changed, and is recorded with the release
+func Redeploy(ctx context.Context, stack auto.Stack, name string, description string, verbose bool) error {
+	args, err := LoadDeploymentArgs(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	return Update(ctx, stack, name, args, &Release{Kind: ReleaseConfig, Description: description}, verbose)
+}
+
+// Scale rolls out an app with a new replica count, keeping its current image and settings
+func Scale(ctx context.Context, stack auto.Stack, name string, replicas int, verbose bool) error {
+	args, err := LoadDeploymentArgs(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	if args.Autoscale != nil {
+		return fmt.Errorf("%s autoscales between %d and %d replicas, change its autoscale settings instead", name, args.Autoscale.Min, args.Autoscale.Max)
+	}
+
+	args.Replicas = &replicas
+	if err := args.Validate(); err != nil {
+		return fmt.Errorf("invalid deployment settings: %v", err)
+	}
+
+	if err := SaveReplicas(ctx, stack, args.Replicas); err != nil {
+		return fmt.Errorf("error saving replicas: %v", err)
+	}
+
+	return Update(ctx, stack, name, args, &Release{Kind: ReleaseConfig, Description: fmt.Sprintf("Scale to %d replicas", replicas)}, verbose)
+}
+
+// Restart replaces every pod of an app with a rolling restart, like kubectl rollout restart
+func Restart(ctx context.Context, stack auto.Stack, name string, verbose bool) error {
+	args, err := LoadDeploymentArgs(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	args.RestartedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := stack.SetConfig(ctx, restartedAtKey, auto.ConfigValue{Value: args.RestartedAt}); err != nil {
+		return fmt.Errorf("error saving restart time: %v", err)
+	}
+
+	return Update(ctx, stack, name, args, &Release{Kind: ReleaseConfig, Description: "Restart"}, verbose)
+}
+
+// ManagesRepository reports whether the stack already holds an ECR repository for the app's images
+func ManagesRepository(ctx context.Context, stack auto.Stack) (bool, error) {
+	state, err := stack.Export(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error reading stack state: %v", err)
+	}
+
+	var deployment struct {
+		Resources []struct {
+			Type string `json:"type"`
+		} `json:"resources"`
+	}
+	if len(state.Deployment) > 0 {
+		if err := json.Unmarshal(state.Deployment, &deployment); err != nil {
+			return false, fmt.Errorf("error reading stack state: %v", err)
+		}
+	}
+
+	for _, resource := range deployment.Resources {
+		if resource.Type == "aws:ecr/repository:Repository" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Update saves the deployment settings to the stack and runs the ploy program against it, recording it as a release
+func Update(ctx context.Context, stack auto.Stack, name string, args *PloyDeploymentArgs, release *Release, verbose bool) error {
+	settings, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("error saving deployment settings: %v", err)
+	}
+	err = stack.SetConfig(ctx, deploymentKey, auto.ConfigValue{Value: string(settings)})
+	if err != nil {
+		return err
+	}
+	err = saveRegistryPassword(ctx, stack, args.Registry)
+	if err != nil {
+		return fmt.Errorf("error saving registry password: %v", err)
+	}
+	err = recordRelease(ctx, stack, args, release)
+	if err != nil {
+		return fmt.Errorf("error recording release: %v", err)
+	}
+
+	if args.Prebuilt {
+		args.KeepRepository, err = ManagesRepository(ctx, stack)
+		if err != nil {
+			return err
+		}
+	}
+
+	workspace := stack.Workspace()
+	err = EnsurePlugins(workspace)
+	if err != nil {
+		return err
+	}
+
+	workspace.SetProgram(Deploy(name, args))
+
+	if len(args.ReleaseCommand) > 0 {
+		stop := followRelease(name)
+		defer stop()
+	}
+
+	// We give the user the option to actually view the Pulumi output
+	var streamer optup.Option
+	if verbose {
+		streamer = optup.ProgressStreams(os.Stdout)
+	} else {
+		upChannel := make(chan events.EngineEvent)
+		go CollectEvents(upChannel)
+
+		streamer = optup.EventStreams(upChannel)
+	}
+
+	message := release.Kind
+	if release.Description != "" {
+		message = release.Description
+	}
+
+	_, err = stack.Up(ctx, streamer, optup.Message(message))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
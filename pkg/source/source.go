@@ -0,0 +1,206 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pulumi "github.com/jaxxstorm/ploy/pkg/pulumi"
+)
+
+// Inspect describes the source in a build context from git, when the context is in a repository
+// The content digest is always calculated, so builds outside of git still get a deterministic tag
+func Inspect(directory string) (*pulumi.Source, error) {
+	digest, err := Digest(directory)
+	if err != nil {
+		return nil, err
+	}
+	source := &pulumi.Source{Digest: digest}
+
+	commit, err := git(directory, "rev-parse", "HEAD")
+	if err != nil {
+		// not a git repository, or git isn't installed
+		return source, nil
+	}
+	source.Commit = commit
+
+	// a detached HEAD, as CI systems often check out, has no branch
+	if branch, err := git(directory, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		source.Branch = branch
+	}
+
+	if author, err := git(directory, "log", "-1", "--format=%an <%ae>"); err == nil {
+		source.Author = author
+	}
+
+	// only changes to files that go into the build context make the image differ from the commit, and that includes
+	// files git ignores but .dockerignore doesn't
+	status, err := gitOutput(directory, "status", "--porcelain", "-z", "--untracked-files=all", "--ignored=matching", "--", ".")
+	if err != nil {
+		return nil, fmt.Errorf("error checking for uncommitted changes: %v", err)
+	}
+	prefix, err := git(directory, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, fmt.Errorf("error checking for uncommitted changes: %v", err)
+	}
+	ignored, err := dockerignore(directory)
+	if err != nil {
+		return nil, err
+	}
+	source.Dirty = changed(status, prefix, ignored)
+
+	return source, nil
+}
+
+// changed reports whether git status -z output for a build context has any path that isn't in .dockerignore
+// Paths are relative to the repository root, so the context's prefix is removed first
+func changed(status string, prefix string, ignored func(string) bool) bool {
+	entries := strings.Split(status, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		// renames and copies are followed by the path they came from
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+		path := strings.TrimSuffix(strings.TrimPrefix(entry[3:], prefix), "/")
+		if !ignored(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Digest returns a sha256 digest of the files in a build context, skipping those in .dockerignore
+func Digest(directory string) (string, error) {
+	ignored, err := dockerignore(directory)
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+	err = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if info.Name() == ".git" || ignored(filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error reading build context %s: %v", directory, err)
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(directory, file))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00%o\x00", filepath.ToSlash(file), info.Mode().Perm())
+
+		f, err := os.Open(filepath.Join(directory, file))
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %v", file, err)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// dockerignore returns a matcher for the patterns in the context's .dockerignore
+// Patterns are matched against each path and its parent directories, negations are honoured in order
+func dockerignore(directory string) (func(string) bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(directory, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return func(string) bool { return false }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading .dockerignore: %v", err)
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return func(path string) bool {
+		ignored := false
+		for _, pattern := range patterns {
+			negate := strings.HasPrefix(pattern, "!")
+			pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, "!"), "/")
+			pattern = strings.TrimSuffix(pattern, "/")
+			if matches(pattern, path) {
+				ignored = !negate
+			}
+		}
+		return ignored
+	}, nil
+}
+
+// matches reports whether a pattern matches a path or any of its parent directories
+func matches(pattern string, path string) bool {
+	for {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		parent := filepath.Dir(path)
+		if parent == "." || parent == path {
+			return false
+		}
+		path = parent
+	}
+}
+
+// git runs a git command in a directory and returns its trimmed output
+func git(directory string, args ...string) (string, error) {
+	out, err := gitOutput(directory, args...)
+	return strings.TrimSpace(out), err
+}
+
+// gitOutput runs a git command in a directory and returns its output as it is
+func gitOutput(directory string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = directory
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,36 @@
+package source
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChanged(t *testing.T) {
+	ignored := func(path string) bool {
+		return path == "node_modules" || strings.HasSuffix(path, ".log")
+	}
+
+	tests := []struct {
+		name   string
+		status string
+		prefix string
+		want   bool
+	}{
+		{name: "clean", status: "", want: false},
+		{name: "modified", status: " M main.go\x00", want: true},
+		{name: "untracked", status: "?? new.go\x00", want: true},
+		{name: "git-ignored", status: "!! .env\x00", want: true},
+		{name: "dockerignored", status: "!! node_modules/\x00?? debug.log\x00", want: false},
+		{name: "dockerignored in a subdirectory", status: "!! app/node_modules/\x00", prefix: "app/", want: false},
+		{name: "rename from a dockerignored path", status: "R  kept.go\x00old.log\x00", want: true},
+		{name: "rename to a dockerignored path", status: "R  new.log\x00main.go\x00", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := changed(test.status, test.prefix, ignored); got != test.want {
+				t.Errorf("changed(%q) = %v, want %v", test.status, got, test.want)
+			}
+		})
+	}
+}